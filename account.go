@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"drive/drive"
+)
+
+// GetAccount returns the Google account MineVCS syncs to, so it's clear whose Drive that is on a shared computer
+func (a *App) GetAccount() (drive.Account, error) {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return drive.Account{}, err
+	}
+	account, err := drive.GetAccount(srv)
+	if err != nil {
+		return drive.Account{}, err
+	}
+	return *account, nil
+}
+
+// SignOut revokes MineVCS's access at Google and forgets the sign in on this machine. if Google can't be
+// reached the sign in is still forgotten here
+func (a *App) SignOut() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := drive.RevokeToken(ctx); err != nil {
+		a.printAndEmit("Could not revoke access at Google, remove MineVCS from your Google account's third-party access if you want to: " + err.Error() + " ⚠️")
+		if err := drive.DeleteToken(); err != nil {
+			return err
+		}
+	}
+	a.printAndEmit("Signed out of Google Drive 👋")
+	a.setSyncStatus("idle", "Signed out")
+	a.emit("authStatus", AuthStatus{State: drive.AuthMissing, Message: authMessages[drive.AuthMissing]})
+	a.emit("signedOut", nil)
+	return nil
+}
+
+// SwitchAccount signs out and starts signing in again, returning the URL to open. Google shows the account
+// chooser so another account can be picked
+func (a *App) SwitchAccount() (string, error) {
+	if err := a.SignOut(); err != nil {
+		return "", err
+	}
+	return a.GoogleAuth()
+}
@@ -1,32 +1,32 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"crypto/sha256"
 	"drive/drive"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/process"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// how long a sign in waits for the browser to come back
+const authTimeout = 10 * time.Minute
+
 type ProgressReader struct {
 	io.Reader
 	Reporter func(bytesRead int64)
 }
 
 type UserData struct {
-	MinecraftLauncher  string `json:"minecraftLauncher"`
-	MinecraftDirectory string `json:"minecraftDirectory"`
-	WorldName          string `json:"worldName"`
+	MinecraftLauncher string        `json:"minecraftLauncher"`
+	Worlds            []WorldStatus `json:"worlds"`
 }
 
 type DefaultPaths struct {
@@ -34,13 +34,35 @@ type DefaultPaths struct {
 	MinecraftSavePath     string `json:"minecraftSavePath"`
 }
 
+// SyncStatus is the last outcome of a sync operation, shown on the Home screen
+type SyncStatus struct {
+	State   string `json:"state"` // "idle", "ok" or "error"
+	Message string `json:"message"`
+	Time    string `json:"time"`
+}
+
 type App struct {
-	ctx                context.Context
-	minecraftLauncher  string
-	minecraftDirectory string
-	worldName          string
-	isMonitoring       bool
-	logs               []string
+	ctx               context.Context
+	mu                sync.Mutex // guards the settings and state below, which the monitor, watcher and outbox worker share with the bindings
+	minecraftLauncher string
+	worlds            []WorldConfig
+	worldStatus       map[string]SyncStatus
+	transfer          TransferSettings
+	profile           ProfileSettings
+	isMonitoring      bool
+	logs              []string
+	syncStatus        SyncStatus
+	outbox            *Outbox
+	pushMu            sync.Mutex // only one upload at a time (monitor, outbox worker, bindings)
+	pushLocks         sync.Map   // world key -> *sync.Mutex held for the whole of a push of that world, see worldPushLock
+	idMu              sync.Mutex // stops two goroutines handing the same world different ids
+	instanceLock      *os.File   // ~/.minevcs/instance.lock, see lockInstance
+	watchKick         chan struct{}
+	auth              *drive.LoopbackAuth // sign in waiting for the browser to come back
+	launches          []*playLaunch       // Play clicks that are syncing or waiting for the game to show up
+	playMu            sync.Mutex          // one Play at a time
+	profileMu         sync.Mutex          // one profile sync at a time, it reads and writes ~/.minevcs/profile.json
+	headless          bool                // run from the command line, there's no frontend to send events to
 }
 
 func (pr *ProgressReader) Read(p []byte) (n int, err error) {
@@ -50,108 +72,147 @@ func (pr *ProgressReader) Read(p []byte) (n int, err error) {
 }
 
 func NewApp() *App {
-	return &App{}
+	return &App{
+		syncStatus:  SyncStatus{State: "idle"},
+		worldStatus: map[string]SyncStatus{},
+		watchKick:   make(chan struct{}, 1),
+	}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	time.Sleep(1500 * time.Millisecond) // gives time for frontend to load
 	a.createMinevcsDirectory()
-	home, _ := os.UserHomeDir()
-	configPath := filepath.Join(home, ".minevcs", "config.json")
-	println("CONFIG PATH: ", configPath)
-	if _, err := os.Stat(configPath); err != nil {
-		if os.IsNotExist(err) {
-			a.printAndEmit("Config file not created yet, create a new one first" + " ❌")
-			return
-		}
-		a.printAndEmit("Config file is corrupted, please create a new one ❌")
+	a.waitForInstanceLock()
+	a.openOutbox()
+	go a.runOutboxWorker()
+	a.outbox.wake()
+	println("CONFIG PATH: ", configFilePath())
+	if _, err := os.Stat(configFilePath()); os.IsNotExist(err) {
+		a.printAndEmit("Config file not created yet, create a new one first" + " ❌")
 		return
 	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		a.printAndEmit("Error reading file. Config file is corrupted, please create a new one ❌")
+	if err := a.loadConfig(); err != nil {
+		fmt.Printf("CONFIG READ ERROR: %v\n", err)
+		a.printAndEmit("Config file could not be read, " + err.Error() + " ❌")
 		return
 	}
+	println("GOT DATA: ", a.minecraftLauncher, len(a.worlds), "worlds")
+	a.reportConfigProblems()
 
-	var config map[string]string
-	err = json.Unmarshal(data, &config)
-	if err != nil {
-		fmt.Printf("JSON UNMARSHALL ERROR: %v\n", err)
-		a.printAndEmit("JSON unmarshal. Config file is corrupted, please create a new one ❌")
-		return
+	if !a.isMonitoring {
+		a.startMinecraftMonitor()
 	}
 
-	a.minecraftLauncher = config["minecraftLauncher"]
-	a.minecraftDirectory = config["minecraftDirectory"]
-	a.worldName = config["worldName"]
-	println("GOT DATA: ", a.minecraftLauncher, a.minecraftDirectory, a.worldName)
+	a.emit("userDataReady", nil)
+	go a.GetAuthStatus()
+}
 
-	if !a.isMonitoring {
-		a.startMinecraftMonitor()
+func (a *App) openOutbox() {
+	home, _ := os.UserHomeDir()
+	outbox, err := loadOutbox(filepath.Join(home, ".minevcs", "outbox"))
+	if err != nil {
+		a.printAndEmit("Error loading queued pushes, starting with an empty queue: " + err.Error() + " ❌")
+		outbox = &Outbox{dir: filepath.Join(home, ".minevcs", "outbox"), kick: make(chan struct{}, 1), uploading: map[string]bool{}}
 	}
+	a.outbox = outbox
+}
 
-	wailsRuntime.EventsEmit(a.ctx, "userDataReady", nil)
+// reads the config file into the app, upgrading it first if an older version wrote it
+func (a *App) loadConfig() error {
+	config, migrated, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if migrated {
+		a.saveMigratedConfig(config)
+	}
+	a.mu.Lock()
+	a.minecraftLauncher = config.MinecraftLauncher
+	a.worlds = config.Worlds
+	a.transfer = config.Transfer
+	a.profile = config.Profile
+	a.mu.Unlock()
+	a.applyTransferSettings()
+	return nil
 }
 
+// pushes every synced world whose local copy is newer than the one on Drive
 func (a *App) PushIfAhead() {
-	if a.minecraftDirectory == "" || a.worldName == "" {
-		return
+	for _, w := range a.worldList() {
+		if w.canPush() {
+			a.pushIfAhead(w)
+		}
 	}
-	home, _ := os.UserHomeDir()
-	worldPath := filepath.Join(home, a.minecraftDirectory, a.worldName)
+}
+
+func (a *App) pushIfAhead(w WorldConfig) {
+	lock := a.worldPushLock(w)
+	lock.Lock()
+	defer lock.Unlock()
+	worldPath := w.path()
 	// check if the world folder exists
 	if _, err := os.Stat(worldPath); os.IsNotExist(err) {
-		a.printAndEmit("World folder not found on local machine (most likely this is the device you are syncing to) ❌")
+		a.printAndEmit(w.Name + ": world folder not found on local machine (most likely this is the device you are syncing to) ❌")
+		return
+	}
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		a.reportSyncError(w, "Push", err)
 		return
 	}
-	inSyncWithCloud, err := a.checkOutOfSync(worldPath)
+	inSyncWithCloud, err := a.checkOutOfSync(w)
 	if err != nil {
 		a.printAndEmit("Error checking world sync status: " + err.Error() + " ❌")
 		return
 	}
 	if !inSyncWithCloud {
-		a.printAndEmit("Local world is ahead of last uploaded world, pushing updated world to Drive ⏳")
-		_, err = a.cloudUpload(a.worldName, a.minecraftDirectory)
+		a.printAndEmit(w.Name + ": local world is ahead of last uploaded world, pushing updated world to Drive ⏳")
+		_, err = a.cloudUpload(w)
 		if err != nil {
-			a.printAndEmit("Error uploading world: " + err.Error() + " ❌")
+			a.reportSyncError(w, "Push", err)
 			return
 		}
 	} else {
-		println("Local world is in sync with last uploaded world")
+		println("Local world is in sync with last uploaded world:", w.Name)
 	}
 }
 
-func (a *App) checkOutOfSync(worldFolder string) (bool, error) {
-	_, srv, err := drive.InitDrive()
-	if err != nil {
-		return false, err
-	}
+// the newest modification time of any file in the folder
+func latestModTime(folder string) (time.Time, error) {
 	var latest time.Time
-
-	err = filepath.Walk(worldFolder, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			modTime := info.ModTime()
-			if modTime.After(latest) {
-				latest = modTime
-			}
+		if !info.IsDir() && info.ModTime().After(latest) {
+			latest = info.ModTime()
 		}
 		return nil
 	})
+	return latest, err
+}
+
+func (a *App) checkOutOfSync(w WorldConfig) (bool, error) {
+	worldFolder := w.path()
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return false, err
+	}
+	latest, err := latestModTime(worldFolder)
 	if err != nil {
 		return false, fmt.Errorf("failed to walk through world folder: %w", err)
 	}
 	latest = latest.UTC()
 
-	lastUploadTime, err := drive.GetLatestUploadTime(srv, a.worldName)
-	if err != nil {
+	zipFile, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld)
+	if errors.Is(err, drive.ErrNotFound) {
 		return false, nil // here the file is not found on cloud, so we can assume that the local world is ahead of the last upload
 	}
-	parsedLastUploadTime, err := time.Parse(time.RFC3339, lastUploadTime)
+	if err != nil {
+		return false, err
+	}
+	parsedLastUploadTime, err := time.Parse(time.RFC3339, zipFile.ModifiedTime)
 	if err != nil {
 		return false, fmt.Errorf("failed to parse last upload time: %w", err)
 	}
@@ -161,200 +222,120 @@ func (a *App) checkOutOfSync(worldFolder string) (bool, error) {
 	return true, nil // true means in sync with the last upload on cloud
 }
 
+// reports whether a Minecraft client is running, whichever launcher started it
 func (a *App) CheckMinecraftRunning() (bool, error) {
-	path := a.minecraftLauncher
-	procs, err := process.Processes()
+	games, err := findGameProcesses()
 	if err != nil {
 		return false, err
 	}
-	for _, p := range procs {
-		exe, err := p.Exe()
-		if err != nil {
-			continue
-		}
-		if strings.Contains(strings.ToLower(exe), strings.ToLower(path)) {
-			return true, nil
-		}
-	}
-	return false, nil
+	return len(games) > 0, nil
 }
 
-func (a *App) unzipFolder(zipFilePath string) (string, error) {
-	zipReader, err := zip.OpenReader(zipFilePath)
-	if err != nil {
-		return "", err
-	}
-	defer zipReader.Close()
-
-	extractDir := strings.TrimSuffix(zipFilePath, ".zip")
-	err = os.MkdirAll(extractDir, os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-
-	for _, file := range zipReader.File {
-		filePath := filepath.Join(extractDir, file.Name)
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(filePath, os.ModePerm)
-			continue
-		}
-
-		srcFile, err := file.Open()
-		if err != nil {
-			return "", err
-		}
-		defer srcFile.Close()
-
-		destFile, err := os.Create(filePath)
-		if err != nil {
-			return "", err
-		}
-		defer destFile.Close()
-
-		if _, err = io.Copy(destFile, srcFile); err != nil {
-			return "", err
-		}
-	}
-
-	return extractDir, nil
-}
-
-func (a *App) zipFolder(sourceDir string) (string, error) {
-	// Compute output zip path
-	parentDir := filepath.Dir(sourceDir)
-	baseName := filepath.Base(sourceDir)
-	zipFilePath := filepath.Join(parentDir, baseName+".zip")
-
-	zipFile, err := os.Create(zipFilePath)
-	if err != nil {
-		return "", err
-	}
-	defer zipFile.Close()
-
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
-
-	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// relPath := strings.TrimPrefix(path, filepath.Dir(sourceDir))
-		relPath := strings.TrimPrefix(path, sourceDir)
-		relPath = strings.TrimPrefix(relPath, string(filepath.Separator))
-
-		if relPath == "" {
-			return nil
-		}
-
-		if info.IsDir() {
-			_, err = zipWriter.Create(relPath + "/")
-			return err
-		}
-
-		file, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		writer, err := zipWriter.Create(relPath)
-		if err != nil {
-			return err
-		}
-
-		_, err = io.Copy(writer, file)
-		return err
-	})
-
-	return zipFilePath, err
-}
-
-func (a *App) cloudUpload(worldName string, minecraftDirectory string) ([]string, error) {
+// the caller holds the world's worldPushLock
+func (a *App) cloudUpload(w WorldConfig) ([]string, error) {
 	// handles the upload of the user's world to the cloud
-	a.printAndEmit("Pushing updated world to Drive. PLEASE WAIT ⌛️")
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, srv, err := drive.InitDrive()
-	if err != nil {
-		return nil, err
-	}
-
-	// lock file logic
-	lockFilePath := filepath.Join(home, ".minevcs", "temp.lock")
-	if _, err := os.Stat(lockFilePath); os.IsNotExist(err) {
-		a.printAndEmit("Lock file not found, please restart the app")
-		return nil, fmt.Errorf("lock file not found")
-	}
-	lockFile, err := os.Open(lockFilePath)
-	if err != nil {
-		return nil, err
-	}
-
-	// begin upload
-	worldPath := filepath.Join(home, minecraftDirectory, worldName)
+	a.printAndEmit("Pushing " + w.Name + " to Drive. PLEASE WAIT ⌛️")
+	worldPath := w.path()
 	// check if the world folder exists
 	if _, err := os.Stat(worldPath); os.IsNotExist(err) {
 		a.printAndEmit("World folder not found on local machine (most likely this is the device you are syncing to) ❌")
 		return nil, fmt.Errorf("world folder not found")
 	}
-	// if so then we can begin the upload by creating a lock file
-	tempLockFile, err := drive.UploadFile(ctx, srv, lockFile, "")
-	if err != nil {
+	// the game may still be saving the world it's closing
+	if err := w.waitUntilClosed("push", worldCloseWait); err != nil {
 		return nil, err
 	}
 
-	// then upload the level.dat file to drive used to hash later to save time (avoiding unnecessary uploads if the world is in sync with cloud aka the user logs in but doesnt change anything in their world and quits the game)
-	levelDatFilePath := filepath.Join(worldPath, "level.dat")
-	levelDatFile, err := os.Open(levelDatFilePath)
-	if err != nil {
-		return nil, err
-	}
-	defer levelDatFile.Close()
-	_, err = drive.UploadFile(ctx, srv, levelDatFile, "")
-	if err != nil {
-		return nil, err
+	online := drive.Reachable()
+	if online {
+		// make sure Drive has room before spending time zipping the world
+		if err := a.preflightPush(worldPath); err != nil {
+			var quotaErr *quotaError
+			if errors.As(err, &quotaErr) {
+				return nil, err
+			}
+			println("Skipping storage preflight:", err.Error())
+		}
 	}
-	a.printAndEmit("Level.dat uploaded (for hashing)")
-	a.printAndEmit("PLEASE WAIT: pushing world to Drive... ⌛️")
 
-	// then zip + upload the world folder
-	zipFilePath, err := a.zipFolder(worldPath)
-	if err != nil {
-		return nil, err
-	}
-	file, err := os.Open(zipFilePath)
+	// snapshot the world first so nothing is lost if the upload can't happen right now
+	entry, err := a.createSnapshot(w)
 	if err != nil {
 		return nil, err
 	}
-	// print file name
-	createdFile, err := drive.UploadFile(ctx, srv, file, "")
-	file.Close()
-	if err != nil {
-		return nil, err
-	}
-	a.printAndEmit("World uploaded successfully to Drive ✅")
-	err = os.Remove(zipFilePath)
-	if err != nil {
-		return nil, err
-	}
-	err = drive.DeleteFile(srv, tempLockFile.Id)
-	if err != nil {
-		a.printAndEmit("Error deleting lock file: " + err.Error() + " ❌")
+	transfer := a.transferSettings()
+	switch {
+	case a.outbox.pending(w):
+		// older pushes are still waiting, this one goes behind them so the worker's conflict check still applies
+		err = errQueued
+	case !online:
+		err = errQueued
+	case transfer.shouldDefer(a.outbox.snapshotSize(entry), time.Now()):
+		err = errDeferred
+	default:
+		err = a.uploadSnapshot(entry)
+	}
+	if err != nil {
+		if queueErr := a.outbox.add(entry, err); queueErr != nil {
+			os.RemoveAll(a.outbox.snapshotDir(entry.ID))
+			return nil, fmt.Errorf("%v (saving the snapshot for later also failed: %v)", err, queueErr)
+		}
+		if errors.Is(err, errDeferred) {
+			a.printAndEmit(fmt.Sprintf("Large world snapshot saved locally, it will be pushed between %s and %s 🌙", transfer.PushWindowStart, transfer.PushWindowEnd))
+		} else {
+			a.printAndEmit("World snapshot saved locally, it will be pushed automatically once Drive is reachable 📦")
+		}
+		a.emitOutbox()
+		a.outbox.wake()
 		return nil, err
 	}
-	return []string{createdFile.Name}, nil
+	os.RemoveAll(a.outbox.snapshotDir(entry.ID))
+	a.setWorldStatus(w, "ok", "Pushed to Drive")
+	return []string{w.Name + ".zip"}, nil
 }
 
+// GoogleAuth starts signing in and returns the URL to open in the browser. Google redirects back to a local
+// listener and "authenticated" is emitted once the token is saved. if nothing can listen the URL is one for
+// the paste flow instead
 func (a *App) GoogleAuth() (string, error) {
-	url, err := drive.Authenticate()
+	auth, err := drive.StartLoopbackAuth()
 	if err != nil {
-		return "", err
+		a.printAndEmit("Sign in can't come back to MineVCS by itself, paste the code from the browser instead: " + err.Error() + " ⚠️")
+		return drive.Authenticate()
+	}
+	a.mu.Lock()
+	if a.auth != nil {
+		a.auth.Close() // only the latest sign in counts
 	}
-	return url, nil
+	a.auth = auth
+	a.mu.Unlock()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+		defer cancel()
+		err := auth.Wait(ctx)
+		a.mu.Lock()
+		if a.auth == auth {
+			a.auth = nil
+		}
+		a.mu.Unlock()
+		switch {
+		case errors.Is(err, drive.ErrAuthCancelled):
+		case err != nil:
+			a.printAndEmit("Sign in failed: " + err.Error() + " ❌")
+			a.emit("authError", err.Error())
+		default:
+			a.printAndEmit("Connected to Google Drive ✅")
+			a.emit("authenticated", nil)
+			a.GetAuthStatus()
+		}
+	}()
+	return auth.URL, nil
+}
+
+// GoogleAuthPaste returns a sign in URL whose code is pasted back into UserAuthCode, for when the browser
+// can't reach the local listener
+func (a *App) GoogleAuthPaste() (string, error) {
+	return drive.Authenticate()
 }
 
 func (a *App) UserAuthCode(code string) error {
@@ -362,118 +343,198 @@ func (a *App) UserAuthCode(code string) error {
 	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
+	go a.GetAuthStatus()
 	return nil
 }
 
 func (a *App) CheckIfAuthenticated() (bool, error) {
-	home, _ := os.UserHomeDir()
-	pathToToken := filepath.Join(home, ".minevcs", "token.json")
-	_, err := os.Stat(pathToToken)
+	return drive.HasToken()
+}
+
+// AuthStatus is what Google said about the saved sign in, shown on the Home screen
+type AuthStatus struct {
+	State   string `json:"state"` // one of the drive.Auth* states
+	Message string `json:"message"`
+}
+
+var authMessages = map[string]string{
+	drive.AuthOK:         "Connected to Google Drive",
+	drive.AuthMissing:    "Not signed in to Google Drive",
+	drive.AuthLocked:     "Enter your passphrase to unlock the Google sign in",
+	drive.AuthExpired:    "Your Google sign in has expired, sign in again to keep syncing",
+	drive.AuthRevoked:    "Google no longer accepts MineVCS's access to your Drive, sign in again to keep syncing",
+	drive.AuthOffline:    "Google can't be reached, the sign in will be checked again later",
+	drive.AuthFullAccess: "MineVCS has access to your whole Drive but only needs the files it created, sign in again to limit it",
+}
+
+// GetAuthStatus checks the saved sign in with Google and tells the Home screen through "authStatus"
+func (a *App) GetAuthStatus() (AuthStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	state, err := drive.CheckAuth(ctx)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
+		return AuthStatus{}, err
+	}
+	status := AuthStatus{State: state, Message: authMessages[state]}
+	if state == drive.AuthExpired || state == drive.AuthRevoked {
+		a.printAndEmit(status.Message + " 🔑")
+	}
+	if state == drive.AuthOK {
+		go a.checkScopeMigration()
+	}
+	a.emit("authStatus", status)
+	return status, nil
+}
+
+// TokenStorage says where the Google sign in is kept, and whether a passphrase is needed to use it
+type TokenStorage struct {
+	Storage         string `json:"storage"` // "keyring" or "encrypted file"
+	NeedsPassphrase bool   `json:"needsPassphrase"`
+}
+
+func (a *App) GetTokenStorage() TokenStorage {
+	storage, locked := drive.TokenStorage()
+	return TokenStorage{Storage: storage, NeedsPassphrase: locked}
+}
+
+// UnlockTokenStorage sets the passphrase of the encrypted token file, used on machines without a keyring
+func (a *App) UnlockTokenStorage(passphrase string) error {
+	if err := drive.SetPassphrase(passphrase); err != nil {
+		return err
+	}
+	a.printAndEmit("Token storage unlocked 🔓")
+	a.mu.Lock()
+	configured := len(a.worlds) > 0
+	a.mu.Unlock()
+	if authenticated, _ := drive.HasToken(); authenticated {
+		a.emit("authenticated", nil)
+		if configured && !a.isMonitoring {
+			a.startMinecraftMonitor()
 		}
-		return false, err
 	}
-	return true, nil
+	return nil
 }
 
-func (a *App) pullWorld() {
+// downloads the world from Drive and swaps it in for the local copy. failures are reported as they happen,
+// the error is returned so callers can hold off starting the game
+func (a *App) pullWorld(w WorldConfig) error {
+	if err := w.waitUntilClosed("pull", 0); err != nil {
+		a.reportSyncError(w, "Pull", err)
+		return err
+	}
 	ctx, srv, err := drive.InitDrive()
 	if err != nil {
 		a.printAndEmit("Error initializing Drive: " + err.Error() + " ❌")
-		return
+		return err
 	}
-	_, err = drive.FindFileByName(srv, "temp.lock")
+	_, err = drive.FindWorldFile(srv, w.ID, drive.KindLock)
 	if err == nil {
 		a.printAndEmit("World upload in progress from another machine, please restart the app and try again soon ❌")
-		return
+		return fmt.Errorf("an upload of %s from another machine is in progress", w.Name)
+	}
+	if !errors.Is(err, drive.ErrNotFound) {
+		a.reportSyncError(w, "Pull", err)
+		return err
+	}
+	a.printAndEmit("Downloading " + w.Name + " from Drive... ⌛️")
+	zipFile, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld)
+	if errors.Is(err, drive.ErrNotFound) {
+		a.printAndEmit("No upload of " + w.Name + " found on Drive (it may not exist yet)")
+		return nil
 	}
-	a.printAndEmit("Downloading world from Drive... ⌛️")
-	worldToDownload := a.worldName
-	zipFile, err := drive.FindFileByName(srv, worldToDownload+".zip")
 	if err != nil {
-		a.printAndEmit("Error finding file: " + err.Error() + " (it may not exist yet)")
-		return
+		a.reportSyncError(w, "Pull", err)
+		return err
 	}
-	if zipFile == nil {
-		a.printAndEmit("No file found with the name: " + worldToDownload + ".zip ❌")
-		return
+	upgrade, err := a.checkPullVersion(w, zipFile)
+	if err != nil {
+		a.reportSyncError(w, "Pull", err)
+		return err
 	}
-	zipFilePath := filepath.Join(os.TempDir(), zipFile.Name)
+	zipFilePath := filepath.Join(os.TempDir(), w.ID+".zip")
 	err = drive.DownloadFile(ctx, srv, zipFile.Id, zipFilePath)
 	if err != nil {
-		a.printAndEmit("Error downloading file: " + err.Error() + " ❌")
-		return
+		os.Remove(zipFilePath)
+		a.reportSyncError(w, "Download", err)
+		return err
 	}
-	extractDir, err := a.unzipFolder(zipFilePath)
+	extractDir, err := a.unzipFolder(zipFilePath, zipFile.AppProperties["legacy"] == "1")
+	os.Remove(zipFilePath)
 	if err != nil {
-		a.printAndEmit("Error extracting zip file: " + err.Error() + " ❌")
-		return
+		a.printAndEmit("Downloaded world failed verification, local world left untouched: " + err.Error() + " ❌")
+		return err
 	}
-	// move the extracted folder to the minecraft directory
-	home, _ := os.UserHomeDir()
-	minecraftPath := filepath.Join(home, a.minecraftDirectory)
-	// check if the minecraft world already exists
-	existingWorldPath := filepath.Join(minecraftPath, a.worldName)
+	// the download can take a while, check again right before touching the world
+	if err := w.waitUntilClosed("pull", 0); err != nil {
+		os.RemoveAll(extractDir)
+		a.reportSyncError(w, "Pull", err)
+		return err
+	}
+	backup, err := a.replaceWorld(w, extractDir, upgrade)
+	if err != nil {
+		return err
+	}
+	if backup != "" {
+		a.printAndEmit("Pulled world is from a newer Minecraft version, local world backed up to " + backup + " 💾")
+	}
+	a.printAndEmit(w.Name + " pulled successfully from Drive ✅")
+	a.rewatch() // the folder being watched was replaced
+	a.setWorldStatus(w, "ok", "Pulled from Drive")
+	return nil
+}
+
+// moves an extracted world into the saves folder in place of the local copy, which is backed up first if
+// backup is set. returns where the backup went, empty if there was nothing to back up
+func (a *App) replaceWorld(w WorldConfig, extractDir string, backup bool) (string, error) {
+	existingWorldPath := w.path()
+	backupPath := ""
 	if _, err := os.Stat(existingWorldPath); err == nil {
+		if backup {
+			if backupPath, err = a.backupWorld(w); err != nil {
+				os.RemoveAll(extractDir)
+				a.printAndEmit("Error backing up " + w.Name + ", local world left untouched: " + err.Error() + " ❌")
+				return "", err
+			}
+		}
 		a.printAndEmit("World already exists, deleting existing world...")
-		err = os.RemoveAll(existingWorldPath)
-		if err != nil {
+		if err = os.RemoveAll(existingWorldPath); err != nil {
 			a.printAndEmit("Error deleting existing world: " + err.Error() + " ❌")
-			return
+			return "", err
 		}
 		a.printAndEmit("Existing world deleted successfully ✅")
 	}
-	// err = os.Rename(extractDir+"/"+a.worldName, filepath.Join(minecraftPath, a.worldName))
-	err = os.Rename(extractDir, filepath.Join(minecraftPath, a.worldName)) // cross platform fix
-	if err != nil {
+	if err := os.MkdirAll(filepath.Dir(existingWorldPath), os.ModePerm); err != nil {
+		a.printAndEmit("Error creating saves folder: " + err.Error() + " ❌")
+		return "", err
+	}
+	if err := os.Rename(extractDir, existingWorldPath); err != nil { // cross platform fix
 		a.printAndEmit("Error moving extracted folder: " + err.Error() + " ❌")
-		return
+		return "", err
 	}
-	a.printAndEmit("World pulled successfully from Drive ✅")
+	return backupPath, nil
 }
 
-func (a *App) SaveUserData(minecraftLauncher string, minecraftDirectory string, worldName string) {
+// saves the launcher path and adds the given world to the synced worlds, worldID as AddWorld takes it
+func (a *App) SaveUserData(minecraftLauncher string, minecraftDirectory string, worldName string, worldID string) {
 	a.printAndEmit("Saving user data locally...")
-	/*
-		userOS := runtime.GOOS
-
-		if userOS == "windows" {
-			// this is where the user fix comes in, we need to double each backslash in each string
-			a.minecraftLauncher = strings.ReplaceAll(minecraftLauncher, `\`, `\\`)
-			a.minecraftDirectory = strings.ReplaceAll(minecraftDirectory, `\`, `\\`)
-		} else {
-			a.minecraftLauncher = minecraftLauncher
-			a.minecraftDirectory = minecraftDirectory
-		}
-	*/
-	a.minecraftLauncher = minecraftLauncher
-	a.minecraftDirectory = minecraftDirectory // aka the save path
-	a.worldName = worldName
 	home, _ := os.UserHomeDir()
 	configPath := filepath.Join(home, ".minevcs")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		a.printAndEmit("Minevcs directory not found, please create a new one first")
 		return
 	}
-	file := filepath.Join(configPath, "config.json")
-	config := map[string]string{
-		"minecraftLauncher":  a.minecraftLauncher,
-		"minecraftDirectory": a.minecraftDirectory,
-		"worldName":          a.worldName,
-		"lastUpdated":        time.Now().Format(time.RFC3339),
-	}
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		a.printAndEmit("Error marshaling config: " + err.Error())
-		return
+	a.mu.Lock()
+	a.minecraftLauncher = resolvePath(minecraftLauncher)
+	a.mu.Unlock()
+	err := a.AddWorld(minecraftDirectory, worldName, worldID) // minecraftDirectory aka the save path
+	if err == nil {
+		err = a.saveConfig()
 	}
-	err = os.WriteFile(file, data, 0644)
 	if err != nil {
 		a.printAndEmit("Error saving user data: " + err.Error())
 	} else {
 		a.printAndEmit("User data saved successfully ✅")
+		a.reportConfigProblems()
 	}
 
 	if !a.isMonitoring {
@@ -481,6 +542,11 @@ func (a *App) SaveUserData(minecraftLauncher string, minecraftDirectory string,
 	}
 }
 
+// name of the copy of a world's level.dat kept on Drive for hashing
+func levelDatName(w WorldConfig) string {
+	return w.Name + ".level.dat"
+}
+
 func (a *App) getHash(file string) (string, error) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -497,17 +563,17 @@ func (a *App) getHash(file string) (string, error) {
 }
 
 func (a *App) GetUserData() (UserData, error) {
-
+	a.mu.Lock()
+	launcher := a.minecraftLauncher
+	a.mu.Unlock()
 	return UserData{
-		MinecraftLauncher:  a.minecraftLauncher,
-		MinecraftDirectory: a.minecraftDirectory,
-		WorldName:          a.worldName,
+		MinecraftLauncher: launcher,
+		Worlds:            a.ListWorlds(),
 	}, nil
 }
 
-func (a *App) checkHashIsSame() (bool, error) {
-	home, _ := os.UserHomeDir()
-	worldPath := filepath.Join(home, a.minecraftDirectory, a.worldName)
+func (a *App) checkHashIsSame(w WorldConfig) (bool, error) {
+	worldPath := w.path()
 	// first time syncing no level.dat file will exist locally
 	if _, err := os.Stat(worldPath + "/level.dat"); os.IsNotExist(err) {
 		a.printAndEmit("No level.dat file found locally, (most likely this is where you're syncing to) ❌")
@@ -525,16 +591,15 @@ func (a *App) checkHashIsSame() (bool, error) {
 		a.printAndEmit("Error initializing Drive: " + err.Error() + " ❌")
 		return false, err
 	}
-	levelDatFile, err := drive.FindFileByName(srv, "level.dat")
-	if err != nil {
-		a.printAndEmit("Error finding file: " + err.Error() + " ❌")
-		return false, err
+	levelDatFile, err := drive.FindWorldFile(srv, w.ID, drive.KindLevelDat)
+	if errors.Is(err, drive.ErrNotFound) {
+		a.printAndEmit("No level.dat found on Drive (nothing has been uploaded yet)")
+		return false, nil
 	}
-	if levelDatFile == nil {
-		a.printAndEmit("No file found with the name: level.dat ❌")
+	if err != nil {
 		return false, err
 	}
-	levelDatFilePath := filepath.Join(os.TempDir(), "level.dat")
+	levelDatFilePath := filepath.Join(os.TempDir(), w.ID+".level.dat")
 	err = drive.DownloadFile(a.ctx, srv, levelDatFile.Id, levelDatFilePath)
 	if err != nil {
 		a.printAndEmit("Error downloading file: " + err.Error() + " ❌")
@@ -548,6 +613,11 @@ func (a *App) checkHashIsSame() (bool, error) {
 	return hashWorld == levelDatHash, nil
 }
 
+// how often the process table is checked for the game starting or exiting where games can't be waited on
+// (see waitForGames). worlds being opened and closed are followed by the world watcher instead, so this only has
+// to catch the game starting before a world loads
+const gamePollInterval = 5 * time.Second
+
 func (a *App) startMinecraftMonitor() {
 	_, err := a.CheckIfAuthenticated()
 	if err != nil {
@@ -556,64 +626,196 @@ func (a *App) startMinecraftMonitor() {
 	}
 	a.printAndEmit("Monitoring Minecraft status... 👀")
 	a.isMonitoring = true
+	go a.runWorldWatcher()
 	go func() {
-		var minecraftWasRunning bool
-		var cancelPushLoop context.CancelFunc
+		// running games by game directory, each one is its own session
+		sessions := map[string]GameProcess{}
+		a.printAndEmit("Minecraft is not running ⌛️")
 
 		for {
-			running, err := a.CheckMinecraftRunning()
+			games, err := findGameProcesses()
 			if err != nil {
 				a.printAndEmit("Error checking Minecraft status: " + err.Error() + " ❌")
-			} else if running {
-				if !minecraftWasRunning {
-					a.printAndEmit("Minecraft is running ✅")
-					minecraftWasRunning = true
-
-					if authenticated, err := a.CheckIfAuthenticated(); err == nil && authenticated {
-						hashIsSame, err := a.checkHashIsSame()
-						if err != nil {
-							a.printAndEmit("Error checking hash: " + err.Error() + " ❌")
-							return
-						}
-						if !hashIsSame {
-							a.pullWorld()
-						} else {
-							a.printAndEmit("World is in sync with last uploaded world, no download required ✅")
-						}
-					}
-					a.printAndEmit("Currently playing. Syncing world: " + a.worldName + " ⌛️")
+				time.Sleep(gamePollInterval)
+				continue
+			}
+			running := map[string]GameProcess{}
+			for _, game := range games {
+				running[game.GameDir] = game
+			}
+			for dir, game := range running {
+				if _, ok := sessions[dir]; !ok {
+					a.gameStarted(game)
 				}
-			} else {
-				if minecraftWasRunning {
-
-					if authenticated, err := a.CheckIfAuthenticated(); err == nil && authenticated {
-						a.printAndEmit("User exited game, pushing world to Drive...")
-						hashIsSame, err := a.checkHashIsSame()
-						if err != nil {
-							a.printAndEmit("Error checking hash: " + err.Error() + " ❌")
-							return
-						}
-						if !hashIsSame {
-							a.cloudUpload(a.worldName, a.minecraftDirectory)
-						} else {
-							a.printAndEmit("World is in sync with last uploaded world, no upload required ✅")
-						}
-					}
-					if cancelPushLoop != nil {
-						cancelPushLoop()
-						cancelPushLoop = nil
-					}
-				} else {
-					a.printAndEmit("Minecraft is not running ⌛️")
+			}
+			for dir, game := range sessions {
+				if _, ok := running[dir]; !ok {
+					a.gameExited(game)
 				}
-				minecraftWasRunning = false
 			}
+			if len(sessions) > 0 && len(running) == 0 {
+				a.printAndEmit("Minecraft is not running ⌛️")
+			}
+			sessions = running
 
-			time.Sleep(2 * time.Second)
+			a.waitForGameChange(sessions)
 		}
 	}()
 }
 
+// blocks until the running games may have changed. with a native watcher that's a game directory's log being
+// written or a running game exiting, elsewhere the process table is simply checked again after a while
+func (a *App) waitForGameChange(running map[string]GameProcess) {
+	var pids []int32
+	for _, game := range running {
+		pids = append(pids, game.PID)
+	}
+	// a running game writes its log all the time, only the directories without one are watched
+	var gameDirs []string
+	for _, dir := range a.gameDirs() {
+		started := false
+		for runningDir := range running {
+			if runningDir == "" || samePath(runningDir, dir) {
+				started = true
+			}
+		}
+		if !started {
+			gameDirs = append(gameDirs, dir)
+		}
+	}
+	err := waitForGames(context.Background(), gameDirs, pids, watchSafetyInterval)
+	if err != nil {
+		if !errors.Is(err, errWatchUnsupported) {
+			println("Waiting for Minecraft failed, checking again shortly:", err.Error())
+		}
+		time.Sleep(gamePollInterval)
+	}
+}
+
+// the game directories of the synced worlds and the synced profile
+func (a *App) gameDirs() []string {
+	var dirs []string
+	add := func(dir string) {
+		for _, d := range dirs {
+			if samePath(d, dir) {
+				return
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	for _, w := range a.worldList() {
+		add(w.gameDir())
+	}
+	if dir, err := a.profileGameDir(); err == nil {
+		add(dir)
+	}
+	return dirs
+}
+
+// pulls the worlds of the game directory a game was just started from
+func (a *App) gameStarted(game GameProcess) {
+	msg := "Minecraft is running"
+	if game.Version != "" {
+		msg += " (" + game.Version + ")"
+	}
+	if game.GameDir != "" {
+		msg += " from " + game.GameDir
+	}
+	a.printAndEmit(msg + " ✅")
+	if launch := a.claimLaunch(game.GameDir); launch != nil {
+		if launch.syncing {
+			// Play is still pulling and will notice the game is already there
+			a.printAndEmit("Minecraft was started while MineVCS is still pulling your worlds, don't open them until the pull has finished ⚠️")
+			a.setSyncStatus("error", "Minecraft was started mid-pull, wait for the pull to finish before opening a world")
+		}
+		return // Play pulled everything before starting the game
+	}
+	if authenticated, err := a.CheckIfAuthenticated(); err != nil || !authenticated {
+		return
+	}
+	warned := false
+	for _, w := range a.worldList() {
+		if w.canPull() && w.runsIn(game.GameDir) {
+			if !warned {
+				a.printAndEmit("Minecraft was started outside MineVCS, pulling worlds now. Don't open them until the pull has finished (use Play next time) ⚠️")
+				warned = true
+			}
+			a.pullIfBehind(w)
+		}
+	}
+	if a.profileRunsIn(game.GameDir) {
+		a.pullProfile()
+	}
+}
+
+// pushes the worlds and profile of the game directory a game just exited from. the world watcher usually pushed
+// each world as it was closed already, this catches any it missed (no watcher, a failed push) and skips the rest
+// as unchanged
+func (a *App) gameExited(game GameProcess) {
+	a.printAndEmit("Minecraft exited")
+	if authenticated, err := a.CheckIfAuthenticated(); err != nil || !authenticated {
+		return
+	}
+	for _, w := range a.worldList() {
+		if w.canPush() && w.runsIn(game.GameDir) {
+			a.pushIfChanged(w)
+		}
+	}
+	if a.profileRunsIn(game.GameDir) {
+		a.pushProfile()
+	}
+}
+
+// pulls the world if the copy on Drive differs from the local one. the error is already reported to the user
+func (a *App) pullIfBehind(w WorldConfig) error {
+	if a.outbox.pending(w) {
+		// pulling now would replace progress that only exists on this machine
+		a.printAndEmit(w.Name + " has a push waiting to be uploaded, not pulling over it ⚠️")
+		a.outbox.wake()
+		return nil
+	}
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		a.reportSyncError(w, "Pull", err)
+		return err
+	}
+	hashIsSame, err := a.checkHashIsSame(w)
+	if err != nil {
+		a.reportSyncError(w, "Pull", err)
+		return err
+	} else if !hashIsSame {
+		err = a.pullWorld(w)
+	} else {
+		a.printAndEmit(w.Name + " is in sync with last uploaded world, no download required ✅")
+		a.setWorldStatus(w, "ok", "In sync")
+	}
+	a.printAndEmit("Currently playing. Syncing world: " + w.Name + " ⌛️")
+	return err
+}
+
+// pushes the world if it changed since the last upload
+func (a *App) pushIfChanged(w WorldConfig) {
+	lock := a.worldPushLock(w)
+	lock.Lock()
+	defer lock.Unlock()
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		a.reportSyncError(w, "Push", err)
+		return
+	}
+	hashIsSame, err := a.checkHashIsSame(w)
+	if err != nil {
+		a.reportSyncError(w, "Push", err)
+	} else if !hashIsSame {
+		if _, err := a.cloudUpload(w); err != nil {
+			a.reportSyncError(w, "Push", err)
+		}
+	} else {
+		a.printAndEmit(w.Name + " is in sync with last uploaded world, no upload required ✅")
+		a.setWorldStatus(w, "ok", "In sync")
+	}
+}
+
 func (a *App) createMinevcsDirectory() {
 	home, _ := os.UserHomeDir()
 	minevcsPath := filepath.Join(home, ".minevcs")
@@ -635,37 +837,131 @@ func (a *App) createMinevcsDirectory() {
 	a.printAndEmit("Initialized Service successfully ✅")
 }
 
+// the lock on ~/.minevcs/instance.lock. the app holds it for as long as it runs and a command line run for as long
+// as the command takes, so the two never read and write the config, the outbox and the token at the same time
+func (a *App) lockInstance() (bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+	f, ok, err := tryLockFile(filepath.Join(home, ".minevcs", "instance.lock"))
+	if err != nil || !ok {
+		return false, err
+	}
+	a.instanceLock = f
+	return true, nil
+}
+
+// blocks until the app holds the instance lock, while a command run in a terminal finishes
+func (a *App) waitForInstanceLock() {
+	waiting := false
+	for {
+		ok, err := a.lockInstance()
+		if err != nil {
+			a.printAndEmit("Could not lock ~/.minevcs, a command run from a terminal may change the config at the same time: " + err.Error() + " ⚠️")
+			return
+		}
+		if ok {
+			return
+		}
+		if !waiting {
+			a.printAndEmit("A minevcs command is running, waiting for it to finish ⏳")
+			waiting = true
+		}
+		time.Sleep(time.Second)
+	}
+}
+
 func (a *App) printAndEmit(msg string) {
 	timestamp := time.Now().Format("15:04:05")
 	full := fmt.Sprintf("[%s] %s", timestamp, msg)
+	a.mu.Lock()
 	a.logs = append(a.logs, full)
+	a.mu.Unlock()
 	println(msg)
-	wailsRuntime.EventsEmit(a.ctx, "log", full)
+	a.emit("log", full)
 }
 
-// checks user's OS and returns respective path(s)
-func (a *App) GetDefaultPaths() (DefaultPaths, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return DefaultPaths{}, err
+// sends an event to the frontend, if there is one
+func (a *App) emit(event string, data interface{}) {
+	if a.headless {
+		return
+	}
+	wailsRuntime.EventsEmit(a.ctx, event, data)
+}
+
+func (a *App) setSyncStatus(state string, message string) {
+	status := SyncStatus{
+		State:   state,
+		Message: message,
+		Time:    time.Now().Format(time.RFC3339),
+	}
+	a.mu.Lock()
+	a.syncStatus = status
+	a.mu.Unlock()
+	a.emit("syncStatus", status)
+}
+
+// logs a failed sync operation and puts the app in the error state. running out of retries gets its own
+// message since that almost always means the network or Drive is down rather than something being wrong locally
+func (a *App) reportSyncError(w WorldConfig, operation string, err error) {
+	if errors.Is(err, errDeferred) {
+		// not a failure, the snapshot is waiting for the push window
+		a.setWorldStatus(w, "idle", "Push postponed until "+a.transferSettings().PushWindowStart)
+		return
+	}
+	if errors.Is(err, errQueued) {
+		// not a failure, the snapshot is in the outbox and the worker pushes it once Drive is reachable
+		a.setWorldStatus(w, "idle", "Push queued until Drive is reachable")
+		return
 	}
+	if drive.IsAuthError(err) {
+		// find out whether the sign in expired or was revoked so the Home screen can ask to sign in again
+		go a.GetAuthStatus()
+	}
+	var openErr *worldOpenError
+	if errors.As(err, &openErr) {
+		// not a failure either, the world watcher pushes it when it's closed
+		a.printAndEmit(openErr.Error() + " ⏸️")
+		a.setWorldStatus(w, "idle", openErr.Error())
+		return
+	}
+	var retryErr *drive.RetryError
+	var msg string
+	if errors.As(err, &retryErr) {
+		msg = fmt.Sprintf("%s failed: Drive could not be reached after %d attempts, check your connection and try again", operation, retryErr.Attempts)
+	} else {
+		msg = operation + " failed: " + err.Error()
+	}
+	a.printAndEmit(w.Name + ": " + msg + " ❌")
+	a.setWorldStatus(w, "error", msg)
+}
+
+func (a *App) GetSyncStatus() SyncStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.syncStatus
+}
 
+// checks user's OS and returns respective path(s)
+func (a *App) GetDefaultPaths() (DefaultPaths, error) {
 	var launcherPath string
 	var savePath string
 
 	if runtime.GOOS == "darwin" {
 		launcherPath = "/Applications/Minecraft.app/Contents/MacOS/launcher"
-		savePath = "/Library/Application Support/minecraft/saves/"
+		savePath = "~/Library/Application Support/minecraft/saves"
 	} else if runtime.GOOS == "windows" {
 		launcherPath = `C:\XboxGames\Minecraft Launcher\Content\Minecraft.exe`
-		savePath = filepath.Join("AppData", "Roaming", ".minecraft", "saves")
+		savePath = `%APPDATA%\.minecraft\saves`
 	} else {
 		launcherPath = "/usr/bin/minecraft-launcher"
-		savePath = filepath.Join(home, ".minecraft", "saves")
+		savePath = "~/.minecraft/saves"
 	}
 
+	// in the form it will be stored in, so it matches the saves folders of the detected instances
 	return DefaultPaths{
 		MinecraftLauncherPath: launcherPath,
-		MinecraftSavePath:     savePath,
+		MinecraftSavePath:     normalizePath(savePath),
 	}, nil
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// name of the manifest written at the root of every world archive
+	manifestName = ".minevcs-manifest.json"
+	// limits applied when extracting an archive pulled from the cloud (protects against archive bombs)
+	maxArchiveFiles        = 250000
+	maxArchiveUncompressed = 64 << 30 // 64 GiB
+)
+
+type ManifestEntry struct {
+	Path   string `json:"path"` // slash separated, relative to the world folder
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the contents of a world snapshot so a pulled archive can be verified before it replaces the local world
+type Manifest struct {
+	CreatedAt string          `json:"createdAt"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// zips sourceDir into destDir/<folder name>.zip and returns the zip's path
+func (a *App) zipFolder(sourceDir string, destDir string) (string, error) {
+	// Compute output zip path
+	baseName := filepath.Base(sourceDir)
+	zipFilePath := filepath.Join(destDir, baseName+".zip")
+
+	zipFile, err := os.Create(zipFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+	manifest := Manifest{CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+
+	err = filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		// zip entries always use forward slashes, otherwise worlds zipped on windows can't be read elsewhere
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			_, err = zipWriter.Create(relPath + "/")
+			return err
+		}
+		// Minecraft recreates it every session, and while the world is open it's locked so it can't be read on windows
+		if relPath == "session.lock" {
+			return nil
+		}
+		// symlinks, devices, pipes etc. never belong in a world folder so they are left out of the archive
+		if !info.Mode().IsRegular() {
+			println("Skipping non regular file:", relPath)
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		writer, err := zipWriter.Create(relPath)
+		if err != nil {
+			return err
+		}
+
+		h := sha256.New()
+		size, err := io.Copy(io.MultiWriter(writer, h), file)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestEntry{
+			Path:   relPath,
+			Size:   size,
+			SHA256: fmt.Sprintf("%x", h.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		zipWriter.Close()
+		return zipFilePath, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		zipWriter.Close()
+		return zipFilePath, err
+	}
+	writer, err := zipWriter.Create(manifestName)
+	if err != nil {
+		zipWriter.Close()
+		return zipFilePath, err
+	}
+	if _, err = writer.Write(data); err != nil {
+		zipWriter.Close()
+		return zipFilePath, err
+	}
+
+	return zipFilePath, zipWriter.Close()
+}
+
+// extracts the archive next to itself and returns the extracted folder. the archive is treated as untrusted:
+// unsafe paths, links and special files are rejected, size/count limits are enforced and the result is checked
+// against the snapshot's manifest. only archives uploaded before there were manifests (legacy) may go without
+// one. on any error nothing is left behind.
+func (a *App) unzipFolder(zipFilePath string, legacy bool) (string, error) {
+	zipReader, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return "", err
+	}
+	defer zipReader.Close()
+
+	if len(zipReader.File) > maxArchiveFiles {
+		return "", fmt.Errorf("archive has too many entries (%d, limit is %d)", len(zipReader.File), maxArchiveFiles)
+	}
+	var declared uint64
+	for _, file := range zipReader.File {
+		declared += file.UncompressedSize64
+		if declared > maxArchiveUncompressed {
+			return "", fmt.Errorf("archive is too large when uncompressed (limit is %d bytes)", int64(maxArchiveUncompressed))
+		}
+	}
+
+	extractDir := strings.TrimSuffix(zipFilePath, ".zip")
+	// never merge into leftovers from an earlier pull
+	if err = os.RemoveAll(extractDir); err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(extractDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := extractArchive(zipReader, extractDir)
+	if err == nil && manifest == nil && !legacy {
+		err = fmt.Errorf("snapshot has no manifest, it wasn't written by MineVCS or was tampered with")
+	}
+	if err == nil {
+		err = verifyManifest(extractDir, manifest)
+	}
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return "", err
+	}
+	if manifest == nil {
+		a.printAndEmit("Snapshot has no manifest (uploaded by an older version), skipping verification ⚠️")
+	}
+
+	return extractDir, nil
+}
+
+// writes every entry of the archive into extractDir and returns the parsed manifest (nil if the archive has none)
+func extractArchive(zipReader *zip.ReadCloser, extractDir string) (*Manifest, error) {
+	var manifest *Manifest
+	var written int64
+	for _, file := range zipReader.File {
+		name, err := sanitizeArchivePath(file.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		mode := file.Mode()
+		if mode&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("archive contains a symlink: %s", file.Name)
+		}
+		if !mode.IsDir() && !mode.IsRegular() {
+			return nil, fmt.Errorf("archive contains a special file: %s", file.Name)
+		}
+
+		if name == manifestName {
+			if manifest, err = readManifest(file); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		filePath := filepath.Join(extractDir, filepath.FromSlash(name))
+		if mode.IsDir() {
+			if err = os.MkdirAll(filePath, os.ModePerm); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return nil, err
+		}
+
+		n, err := extractFile(file, filePath, maxArchiveUncompressed-written)
+		if err != nil {
+			return nil, err
+		}
+		written += n
+	}
+	return manifest, nil
+}
+
+// copies a single entry to disk, reading at most limit bytes regardless of what the entry header claims
+func extractFile(file *zip.File, filePath string, limit int64) (int64, error) {
+	srcFile, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer srcFile.Close()
+
+	// O_EXCL so a duplicate entry can't overwrite a file that was already extracted
+	destFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer destFile.Close()
+
+	n, err := io.Copy(destFile, io.LimitReader(srcFile, limit+1))
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("archive is too large when uncompressed (limit is %d bytes)", int64(maxArchiveUncompressed))
+	}
+	return n, nil
+}
+
+// returns the cleaned, slash separated relative path of an archive entry or an error if it could escape the extraction folder
+func sanitizeArchivePath(name string) (string, error) {
+	// older windows builds wrote entries with backslashes
+	cleaned := strings.ReplaceAll(name, `\`, "/")
+	if cleaned == "" || strings.HasPrefix(cleaned, "/") || filepath.VolumeName(cleaned) != "" || strings.Contains(cleaned, ":") {
+		return "", fmt.Errorf("archive contains an absolute path: %s", name)
+	}
+	for _, part := range strings.Split(cleaned, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("archive contains a path traversal: %s", name)
+		}
+	}
+	cleaned = path.Clean(cleaned)
+	if cleaned == "." {
+		return "", fmt.Errorf("archive contains an empty path: %s", name)
+	}
+	return cleaned, nil
+}
+
+func readManifest(file *zip.File) (*Manifest, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	manifest := &Manifest{}
+	// a manifest is tiny, anything bigger than this is not one of ours
+	if err = json.NewDecoder(io.LimitReader(r, 64<<20)).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("snapshot manifest is corrupted: %w", err)
+	}
+	return manifest, nil
+}
+
+// checks that the extracted folder contains exactly the files listed in the manifest with matching sizes and hashes
+func verifyManifest(extractDir string, manifest *Manifest) error {
+	if manifest == nil {
+		return nil
+	}
+	expected := make(map[string]ManifestEntry, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		expected[entry.Path] = entry
+	}
+
+	err := filepath.Walk(extractDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(extractDir, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		entry, ok := expected[relPath]
+		if !ok {
+			return fmt.Errorf("file not listed in snapshot manifest: %s", relPath)
+		}
+		delete(expected, relPath)
+		if info.Size() != entry.Size {
+			return fmt.Errorf("size mismatch for %s (expected %d, got %d)", relPath, entry.Size, info.Size())
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err = io.Copy(h, f); err != nil {
+			return err
+		}
+		if fmt.Sprintf("%x", h.Sum(nil)) != entry.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for missing := range expected {
+		return fmt.Errorf("file missing from snapshot: %s", missing)
+	}
+	return nil
+}
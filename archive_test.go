@@ -0,0 +1,304 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeArchivePath(t *testing.T) {
+	valid := map[string]string{
+		"level.dat":              "level.dat",
+		"region/r.0.0.mca":       "region/r.0.0.mca",
+		`region\r.0.0.mca`:       "region/r.0.0.mca",
+		"./data/raids.dat":       "data/raids.dat",
+		"region//r.0.0.mca":      "region/r.0.0.mca",
+		"playerdata/":            "playerdata",
+		"..level.dat":            "..level.dat",
+		"DIM-1/region/r.0.0.mca": "DIM-1/region/r.0.0.mca",
+	}
+	for name, want := range valid {
+		got, err := sanitizeArchivePath(name)
+		if err != nil || got != want {
+			t.Errorf("%q = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{
+		"",
+		".",
+		"./",
+		"..",
+		"../level.dat",
+		"region/../../level.dat",
+		`..\level.dat`,
+		`region\..\..\level.dat`,
+		"/etc/passwd",
+		`\Windows\System32`,
+		`\\server\share\level.dat`,
+		"C:/level.dat",
+		`C:\level.dat`,
+		"C:level.dat",
+		"region/C:level.dat",
+		"level.dat:stream",
+	} {
+		if got, err := sanitizeArchivePath(name); err == nil {
+			t.Errorf("%q was accepted as %q", name, got)
+		}
+	}
+}
+
+// a zip entry: a file with content unless mode says otherwise
+type testEntry struct {
+	name    string
+	content string
+	mode    os.FileMode
+}
+
+func writeTestZip(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			header.SetMode(e.mode)
+		}
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(e.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "World.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func headlessApp() *App {
+	a := NewApp()
+	a.headless = true
+	return a
+}
+
+// zips a small world the way a push does
+func zipTestWorld(t *testing.T) string {
+	t.Helper()
+	world := filepath.Join(t.TempDir(), "World")
+	for name, content := range map[string]string{
+		"level.dat":        "level",
+		"region/r.0.0.mca": "chunks",
+		"session.lock":     "locked",
+	} {
+		path := filepath.Join(world, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zipPath, err := headlessApp().zipFolder(world, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+func TestUnzipRoundTrip(t *testing.T) {
+	extracted, err := headlessApp().unzipFolder(zipTestWorld(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(extracted, "region", "r.0.0.mca"))
+	if err != nil || string(data) != "chunks" {
+		t.Errorf("region = %q, %v", data, err)
+	}
+	for _, name := range []string{"session.lock", manifestName} {
+		if _, err := os.Stat(filepath.Join(extracted, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+}
+
+// rewrites a zip entry by entry, letting edit change or drop (nil) each one
+func rewriteZip(t *testing.T, src string, edit func(name string, content []byte) []byte) string {
+	t.Helper()
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var entries []testEntry
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content bytes.Buffer
+		content.ReadFrom(rc)
+		rc.Close()
+		if edited := edit(f.Name, content.Bytes()); edited != nil {
+			entries = append(entries, testEntry{name: f.Name, content: string(edited), mode: f.Mode()})
+		}
+	}
+	return writeTestZip(t, entries)
+}
+
+func TestUnzipManifest(t *testing.T) {
+	original := zipTestWorld(t)
+	keep := func(name string, content []byte) []byte { return content }
+	cases := map[string]struct {
+		zip    string
+		legacy bool
+		err    string
+	}{
+		"intact": {zip: rewriteZip(t, original, keep)},
+		"no manifest": {zip: rewriteZip(t, original, func(name string, content []byte) []byte {
+			if name == manifestName {
+				return nil
+			}
+			return content
+		}), err: "no manifest"},
+		"no manifest, legacy upload": {zip: rewriteZip(t, original, func(name string, content []byte) []byte {
+			if name == manifestName {
+				return nil
+			}
+			return content
+		}), legacy: true},
+		"changed file": {zip: rewriteZip(t, original, func(name string, content []byte) []byte {
+			if name == "level.dat" {
+				return []byte("LEVEL")
+			}
+			return content
+		}), err: "checksum mismatch"},
+		"missing file": {zip: rewriteZip(t, original, func(name string, content []byte) []byte {
+			if name == "level.dat" {
+				return nil
+			}
+			return content
+		}), err: "missing from snapshot"},
+		"extra file": {zip: writeTestZip(t, append(entriesOf(t, original), testEntry{name: "data/extra.dat", content: "x"})), err: "not listed"},
+		// a manifest doesn't make a legacy upload trusted any less
+		"changed file, legacy upload": {zip: rewriteZip(t, original, func(name string, content []byte) []byte {
+			if name == "level.dat" {
+				return []byte("LEVEL")
+			}
+			return content
+		}), legacy: true, err: "checksum mismatch"},
+	}
+	for name, c := range cases {
+		extracted, err := headlessApp().unzipFolder(c.zip, c.legacy)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: err = %v, want %q", name, err, c.err)
+		}
+		if extracted != "" {
+			t.Errorf("%s: extracted to %s", name, extracted)
+		}
+		if _, err := os.Stat(strings.TrimSuffix(c.zip, ".zip")); !os.IsNotExist(err) {
+			t.Errorf("%s: the rejected archive was left extracted", name)
+		}
+	}
+}
+
+func entriesOf(t *testing.T, path string) []testEntry {
+	t.Helper()
+	var entries []testEntry
+	rewriteZip(t, path, func(name string, content []byte) []byte {
+		entries = append(entries, testEntry{name: name, content: string(content)})
+		return content
+	})
+	return entries
+}
+
+func TestUnzipRejects(t *testing.T) {
+	cases := map[string]struct {
+		entries []testEntry
+		err     string
+	}{
+		"traversal":        {[]testEntry{{name: "../evil.dat", content: "x"}}, "path traversal"},
+		"absolute":         {[]testEntry{{name: "/tmp/evil.dat", content: "x"}}, "absolute path"},
+		"drive letter":     {[]testEntry{{name: `C:\evil.dat`, content: "x"}}, "absolute path"},
+		"symlink":          {[]testEntry{{name: "region", content: "/etc", mode: os.ModeSymlink | 0777}}, "symlink"},
+		"named pipe":       {[]testEntry{{name: "level.dat", mode: os.ModeNamedPipe | 0644}}, "special file"},
+		"device":           {[]testEntry{{name: "level.dat", mode: os.ModeDevice | 0644}}, "special file"},
+		"duplicate":        {[]testEntry{{name: "level.dat", content: "a"}, {name: "level.dat", content: "b"}}, "exists"},
+		"duplicate by \\":  {[]testEntry{{name: "region/r.0.0.mca", content: "a"}, {name: `region\r.0.0.mca`, content: "b"}}, "exists"},
+		"corrupt manifest": {[]testEntry{{name: manifestName, content: "{"}}, "manifest is corrupted"},
+	}
+	for name, c := range cases {
+		zipPath := writeTestZip(t, c.entries)
+		_, err := headlessApp().unzipFolder(zipPath, true)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: err = %v, want %q", name, err, c.err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(zipPath), "..", "evil.dat")); err == nil {
+			t.Errorf("%s: wrote outside the extraction folder", name)
+		}
+	}
+}
+
+func TestUnzipLimits(t *testing.T) {
+	dir := t.TempDir()
+	write := func(build func(w *zip.Writer)) string {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		build(w)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "World.zip")
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tooMany := write(func(w *zip.Writer) {
+		for i := 0; i <= maxArchiveFiles; i++ {
+			w.CreateRaw(&zip.FileHeader{Name: "f", Method: zip.Store})
+		}
+	})
+	if _, err := headlessApp().unzipFolder(tooMany, true); err == nil || !strings.Contains(err.Error(), "too many entries") {
+		t.Errorf("%d entries: err = %v", maxArchiveFiles+1, err)
+	}
+
+	// the headers claim more than the limit, nothing has to be written to notice
+	tooLarge := write(func(w *zip.Writer) {
+		for _, name := range []string{"region/r.0.0.mca", "region/r.0.1.mca"} {
+			w.CreateRaw(&zip.FileHeader{Name: name, Method: zip.Store, UncompressedSize64: maxArchiveUncompressed/2 + 1})
+		}
+	})
+	if _, err := headlessApp().unzipFolder(tooLarge, true); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("declared size over the limit: err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "World")); !os.IsNotExist(err) {
+		t.Error("an archive over the limits was extracted")
+	}
+
+	// an entry that lies about its size is cut off at the limit however much it really holds
+	lying := write(func(w *zip.Writer) {
+		f, _ := w.Create("level.dat")
+		f.Write(bytes.Repeat([]byte("x"), 100))
+	})
+	r, err := zip.OpenReader(lying)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	n, err := extractFile(r.File[0], filepath.Join(dir, "level.dat"), 10)
+	if err == nil || !strings.Contains(err.Error(), "too large") || n > 11 {
+		t.Errorf("100 bytes with 10 left: read %d, err = %v", n, err)
+	}
+}
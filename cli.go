@@ -0,0 +1,767 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"drive/drive"
+)
+
+// exit codes of the command line mode, so scripts can tell what happened without reading the output
+const (
+	exitOK      = 0
+	exitFailed  = 1 // something couldn't be synced or read
+	exitUsage   = 2 // unknown command or bad arguments
+	exitAuth    = 3 // not signed in, or the sign in needs attention
+	exitPending = 4 // nothing failed, but some pushes were queued (Drive unreachable or waiting for the push window)
+	exitBusy    = 5 // the app or another command is running, nothing was done
+)
+
+// errNotSignedIn means there's no saved Google sign in at all
+var errNotSignedIn = errors.New("not signed in to Google Drive, run minevcs auth login first")
+
+// errBusy means the app or another command holds the instance lock
+var errBusy = errors.New("the MineVCS app or another minevcs command is running, close it or wait for it to finish")
+
+// the encrypted token file can't ask for its passphrase from a script, it's read from here instead
+const passphraseEnv = "MINEVCS_PASSPHRASE"
+
+const cliUsage = `Usage: minevcs <command> [arguments] [--json]
+
+Runs without opening the window, using the same config and Google sign in as the app.
+
+Commands:
+  status                      sign in state and whether each synced world is ahead of or behind Drive
+  worlds [--cloud]            the synced worlds, --cloud adds every world on Drive
+  push [--force] [world...]   push the worlds that changed since the last push, all of them by default
+  pull [--force] [world...]   pull the worlds that changed on Drive, all of them by default
+  history <world>             the copies of a world that can be restored
+  restore <world> <id>        replace the local world with a copy from history (backed up first)
+  auth status                 check the Google sign in
+  auth login [--paste]        sign in, --paste to type in the code when the browser is on another machine
+  auth logout                 sign out and revoke MineVCS's access
+
+A world is given by its name, or by its folder when two synced worlds share a name.
+--json prints the result as JSON on stdout, progress always goes to stderr.
+
+Exit codes: 0 ok, 1 failed, 2 bad arguments, 3 not signed in or sign in expired,
+4 pushes queued until Drive is reachable or the push window opens, 5 the app or another command is running.
+The passphrase of an encrypted token file is read from ` + passphraseEnv + `.
+`
+
+// the flags every command accepts, each uses the ones that apply to it
+type cliOptions struct {
+	json  bool
+	force bool // push or pull even when nothing seems to have changed
+	cloud bool // worlds also lists what's on Drive
+	paste bool // auth login by pasting the code
+}
+
+type cliCommand func(a *App, args []string, opts cliOptions) int
+
+var cliCommands = map[string]cliCommand{
+	"status":  cliStatus,
+	"worlds":  cliWorlds,
+	"push":    cliPush,
+	"pull":    cliPull,
+	"history": cliHistory,
+	"restore": cliRestore,
+	"auth":    cliAuth,
+}
+
+// reports whether the program was started as "minevcs <command>" rather than as the app
+func isCLICommand(arg string) bool {
+	_, ok := cliCommands[arg]
+	return ok || arg == "help" || arg == "-h" || arg == "--help"
+}
+
+func runCLI(args []string) int {
+	attachConsole()
+	command, ok := cliCommands[args[0]]
+	if !ok {
+		fmt.Print(cliUsage)
+		return exitOK
+	}
+	flags := flag.NewFlagSet("minevcs "+args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var opts cliOptions
+	flags.BoolVar(&opts.json, "json", false, "")
+	flags.BoolVar(&opts.force, "force", false, "")
+	flags.BoolVar(&opts.cloud, "cloud", false, "")
+	flags.BoolVar(&opts.paste, "paste", false, "")
+	positional, err := parseCLIFlags(flags, args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "minevcs:", err)
+		fmt.Fprint(os.Stderr, cliUsage)
+		return exitUsage
+	}
+	a, err := newHeadlessApp()
+	if errors.Is(err, errBusy) {
+		return cliFail(opts.json, exitBusy, err)
+	}
+	if err != nil {
+		return cliFail(opts.json, exitFailed, err)
+	}
+	return command(a, positional, opts)
+}
+
+// parses flags wherever they are, so "push MyWorld --json" works as well as "push --json MyWorld"
+func parseCLIFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// the app as the command line uses it: no window, no monitor and no outbox worker, just the config,
+// the queued pushes and the token storage. it refuses to start while the app or another command is running
+func newHeadlessApp() (*App, error) {
+	a := NewApp()
+	a.headless = true
+	a.ctx = context.Background()
+	a.createMinevcsDirectory()
+	ok, err := a.lockInstance()
+	if err != nil {
+		return nil, fmt.Errorf("could not lock ~/.minevcs: %w", err)
+	}
+	if !ok {
+		return nil, errBusy
+	}
+	a.openOutbox()
+	if err := a.loadConfig(); err != nil {
+		return nil, fmt.Errorf("config file could not be read, %w", err)
+	}
+	return a, nil
+}
+
+// prints v as JSON, or hands it to text to print for people
+func cliPrint(asJSON bool, v interface{}, text func(w io.Writer)) {
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(v)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	text(w)
+	w.Flush()
+}
+
+func cliFail(asJSON bool, code int, err error) int {
+	if asJSON {
+		cliPrint(true, map[string]string{"error": err.Error()}, nil)
+	} else {
+		fmt.Fprintln(os.Stderr, "minevcs:", err)
+	}
+	return code
+}
+
+// exit code for an error from Drive
+func errorCode(err error) int {
+	if errors.Is(err, errNotSignedIn) || errors.Is(err, drive.ErrPassphraseRequired) || errors.Is(err, drive.ErrWrongPassphrase) || drive.IsAuthError(err) {
+		return exitAuth
+	}
+	return exitFailed
+}
+
+// makes the saved sign in usable, unlocking the encrypted token file with the passphrase from the environment
+func unlockTokenStorage() error {
+	if _, locked := drive.TokenStorage(); !locked {
+		return nil
+	}
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		if saved, err := drive.HasToken(); err == nil && !saved {
+			return nil // nothing to unlock
+		}
+		return fmt.Errorf("the Google sign in is kept in an encrypted file, set %s to its passphrase: %w", passphraseEnv, drive.ErrPassphraseRequired)
+	}
+	return drive.SetPassphrase(passphrase)
+}
+
+// fails unless there's a saved sign in that can be read
+func requireSignIn() error {
+	if err := unlockTokenStorage(); err != nil {
+		return err
+	}
+	signedIn, err := drive.HasToken()
+	if err != nil {
+		return err
+	}
+	if !signedIn {
+		return errNotSignedIn
+	}
+	return nil
+}
+
+// picks the synced worlds named on the command line, or every world allowed when none are named
+func (a *App) cliSelectWorlds(names []string, allowed func(WorldConfig) bool) ([]WorldConfig, error) {
+	worlds := a.worldList()
+	if len(names) == 0 {
+		var selected []WorldConfig
+		for _, w := range worlds {
+			if allowed(w) {
+				selected = append(selected, w)
+			}
+		}
+		return selected, nil
+	}
+	var selected []WorldConfig
+	for _, name := range names {
+		var matches []WorldConfig
+		for _, w := range worlds {
+			if w.Name == name || samePath(w.path(), resolvePath(name)) {
+				matches = append(matches, w)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("%s is not a synced world, see minevcs worlds", name)
+		case 1:
+			selected = append(selected, matches[0])
+		default:
+			return nil, fmt.Errorf("%d synced worlds are named %s, give the world's folder instead", len(matches), name)
+		}
+	}
+	return selected, nil
+}
+
+// CLIWorld is a synced world as status and worlds print it
+type CLIWorld struct {
+	Name          string `json:"name"`
+	ID            string `json:"id,omitempty"`
+	SavesDir      string `json:"savesDir"`
+	Path          string `json:"path"`
+	Policy        string `json:"policy"`
+	Pending       bool   `json:"pending"` // has a push waiting in the outbox
+	State         string `json:"state,omitempty"`
+	LocalModified string `json:"localModified,omitempty"`
+	DriveModified string `json:"driveModified,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// how a world compares to its copy on Drive
+const (
+	StateInSync    = "in sync"
+	StateAhead     = "ahead"      // changed here since the last push
+	StateBehind    = "behind"     // changed on Drive since the last pull
+	StateNotPushed = "not pushed" // only on this machine
+	StateNotPulled = "not pulled" // only on Drive
+	StateMissing   = "missing"    // neither here nor on Drive
+	StateUnknown   = "unknown"
+)
+
+func (a *App) cliWorld(w WorldConfig) CLIWorld {
+	return CLIWorld{
+		Name:     w.Name,
+		ID:       w.ID,
+		SavesDir: w.SavesDir,
+		Path:     w.path(),
+		Policy:   w.Policy,
+		Pending:  a.outbox.pending(w),
+	}
+}
+
+// compares the world with its copy on Drive the same way push and pull decide whether to do anything
+func (a *App) worldState(w WorldConfig) (CLIWorld, error) {
+	world := a.cliWorld(w)
+	world.State = StateUnknown
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		return world, err
+	}
+	world.ID = w.ID
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return world, err
+	}
+	local, localErr := latestModTime(w.path())
+	if localErr == nil {
+		world.LocalModified = local.UTC().Format(time.RFC3339)
+	}
+	zipFile, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld)
+	if err != nil && !errors.Is(err, drive.ErrNotFound) {
+		return world, err
+	}
+	if zipFile != nil {
+		world.DriveModified = zipFile.ModifiedTime
+	}
+	switch {
+	case localErr != nil && zipFile == nil:
+		world.State = StateMissing
+	case zipFile == nil:
+		world.State = StateNotPushed
+	case localErr != nil:
+		world.State = StateNotPulled
+	default:
+		inSync, err := a.checkOutOfSync(w)
+		if err != nil {
+			return world, err
+		}
+		if !inSync {
+			world.State = StateAhead
+			break
+		}
+		same, err := a.checkHashIsSame(w)
+		if err != nil {
+			return world, err
+		}
+		world.State = StateInSync
+		if !same {
+			world.State = StateBehind
+		}
+	}
+	return world, nil
+}
+
+// CLIStatus is what minevcs status prints
+type CLIStatus struct {
+	Auth    AuthStatus     `json:"auth"`
+	Account *drive.Account `json:"account,omitempty"`
+	Storage string         `json:"storage"` // where the token is kept
+	Worlds  []CLIWorld     `json:"worlds"`
+	Queued  int            `json:"queued"` // pushes waiting in the outbox
+}
+
+func cliStatus(a *App, args []string, opts cliOptions) int {
+	if len(args) > 0 {
+		return cliFail(opts.json, exitUsage, fmt.Errorf("status takes no arguments"))
+	}
+	code := exitOK
+	status := CLIStatus{Worlds: []CLIWorld{}, Queued: len(a.outbox.list())}
+	unlockErr := unlockTokenStorage()
+	status.Storage, _ = drive.TokenStorage()
+	auth, err := a.GetAuthStatus()
+	if err != nil {
+		return cliFail(opts.json, errorCode(err), err)
+	}
+	status.Auth = auth
+	if unlockErr != nil && status.Auth.State == drive.AuthLocked {
+		status.Auth.Message = unlockErr.Error()
+	}
+	signedIn := status.Auth.State == drive.AuthOK || status.Auth.State == drive.AuthFullAccess
+	if signedIn {
+		if account, err := a.GetAccount(); err == nil {
+			status.Account = &account
+		}
+	} else if status.Auth.State != drive.AuthOffline {
+		code = exitAuth
+	}
+	for _, w := range a.worldList() {
+		world := a.cliWorld(w)
+		if signedIn {
+			var err error
+			if world, err = a.worldState(w); err != nil {
+				world.Error = err.Error()
+				if code == exitOK {
+					code = errorCode(err)
+				}
+			}
+		}
+		status.Worlds = append(status.Worlds, world)
+	}
+	cliPrint(opts.json, status, func(w io.Writer) {
+		fmt.Fprintln(w, status.Auth.Message)
+		if status.Account != nil {
+			fmt.Fprintf(w, "Account:\t%s (%s)\n", status.Account.Name, status.Account.Email)
+		}
+		fmt.Fprintf(w, "Token kept in:\t%s\n", status.Storage)
+		if status.Queued > 0 {
+			fmt.Fprintf(w, "Queued pushes:\t%d\n", status.Queued)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "WORLD\tPOLICY\tSTATE\tPATH")
+		for _, world := range status.Worlds {
+			state := world.State
+			if world.Pending {
+				state += ", push queued"
+			}
+			if world.Error != "" {
+				state = "error: " + world.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", world.Name, world.Policy, state, world.Path)
+		}
+	})
+	return code
+}
+
+// CLIWorlds is what minevcs worlds prints. Cloud is only filled in with --cloud
+type CLIWorlds struct {
+	Worlds []CLIWorld         `json:"worlds"`
+	Cloud  []drive.CloudWorld `json:"cloud,omitempty"`
+}
+
+func cliWorlds(a *App, args []string, opts cliOptions) int {
+	if len(args) > 0 {
+		return cliFail(opts.json, exitUsage, fmt.Errorf("worlds takes no arguments"))
+	}
+	result := CLIWorlds{Worlds: []CLIWorld{}}
+	for _, w := range a.worldList() {
+		result.Worlds = append(result.Worlds, a.cliWorld(w))
+	}
+	if opts.cloud {
+		if err := requireSignIn(); err != nil {
+			return cliFail(opts.json, errorCode(err), err)
+		}
+		cloud, err := a.ListCloudWorlds()
+		if err != nil {
+			return cliFail(opts.json, errorCode(err), err)
+		}
+		result.Cloud = cloud
+	}
+	cliPrint(opts.json, result, func(w io.Writer) {
+		fmt.Fprintln(w, "WORLD\tPOLICY\tID\tPATH")
+		for _, world := range result.Worlds {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", world.Name, world.Policy, world.ID, world.Path)
+		}
+		if opts.cloud {
+			synced := map[string]bool{}
+			for _, world := range result.Worlds {
+				synced[world.ID] = true
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "ON DRIVE\tLAST PUSH\tID\tSYNCED HERE")
+			for _, cw := range result.Cloud {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", cw.Name, cw.ModifiedTime, cw.ID, synced[cw.ID])
+			}
+		}
+	})
+	return exitOK
+}
+
+// CLIResult is what push, pull and restore did with one world
+type CLIResult struct {
+	World  string `json:"world"`
+	Path   string `json:"path"`
+	Result string `json:"result"` // pushed, pulled, restored, in sync, queued, skipped or failed
+	Error  string `json:"error,omitempty"`
+}
+
+// prints the results and works out the exit code: the worst of failed, auth and queued
+func cliResults(asJSON bool, results []CLIResult, errs []error) int {
+	code := exitOK
+	for i, result := range results {
+		switch {
+		case result.Result == "failed" && errorCode(errs[i]) == exitAuth:
+			code = exitAuth
+		case result.Result == "failed" && code != exitAuth:
+			code = exitFailed
+		case result.Result == "queued" && code == exitOK:
+			code = exitPending
+		}
+	}
+	cliPrint(asJSON, map[string]interface{}{"results": results}, func(w io.Writer) {
+		for _, result := range results {
+			line := result.World + "\t" + result.Result
+			if result.Error != "" {
+				line += "\t" + result.Error
+			}
+			fmt.Fprintln(w, line)
+		}
+	})
+	return code
+}
+
+func cliPush(a *App, args []string, opts cliOptions) int {
+	worlds, err := a.cliSelectWorlds(args, WorldConfig.canPush)
+	if err != nil {
+		return cliFail(opts.json, exitUsage, err)
+	}
+	if err := requireSignIn(); err != nil {
+		return cliFail(opts.json, errorCode(err), err)
+	}
+	// whatever an earlier run queued goes first
+	a.flushOutbox()
+	online := drive.Reachable()
+	results := []CLIResult{}
+	var errs []error
+	for _, w := range worlds {
+		result, err := a.cliPushWorld(w, online, opts.force)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		errs = append(errs, err)
+	}
+	return cliResults(opts.json, results, errs)
+}
+
+func (a *App) cliPushWorld(w WorldConfig, online bool, force bool) (CLIResult, error) {
+	result := CLIResult{World: w.Name, Path: w.path(), Result: "failed"}
+	if !w.canPush() {
+		result.Result = "skipped"
+		return result, fmt.Errorf("its policy on this machine is %s", w.Policy)
+	}
+	lock := a.worldPushLock(w)
+	lock.Lock()
+	defer lock.Unlock()
+	if online || w.ID == "" {
+		resolved, err := a.resolveWorld(w)
+		if err != nil {
+			return result, err
+		}
+		w = resolved
+	}
+	if online && !force {
+		inSync, err := a.checkOutOfSync(w)
+		if err != nil {
+			return result, err
+		}
+		if inSync {
+			result.Result = "in sync"
+			return result, nil
+		}
+	}
+	_, err := a.cloudUpload(w)
+	switch {
+	case errors.Is(err, errQueued) || errors.Is(err, errDeferred):
+		result.Result = "queued"
+		result.Error = err.Error()
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+	result.Result = "pushed"
+	return result, nil
+}
+
+func cliPull(a *App, args []string, opts cliOptions) int {
+	worlds, err := a.cliSelectWorlds(args, WorldConfig.canPull)
+	if err != nil {
+		return cliFail(opts.json, exitUsage, err)
+	}
+	if err := requireSignIn(); err != nil {
+		return cliFail(opts.json, errorCode(err), err)
+	}
+	results := []CLIResult{}
+	var errs []error
+	for _, w := range worlds {
+		result, err := a.cliPullWorld(w, opts.force)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		errs = append(errs, err)
+	}
+	return cliResults(opts.json, results, errs)
+}
+
+func (a *App) cliPullWorld(w WorldConfig, force bool) (CLIResult, error) {
+	result := CLIResult{World: w.Name, Path: w.path(), Result: "failed"}
+	if !w.canPull() {
+		result.Result = "skipped"
+		return result, fmt.Errorf("its policy on this machine is %s", w.Policy)
+	}
+	if a.outbox.pending(w) {
+		// pulling now would replace progress that only exists on this machine
+		result.Result = "skipped"
+		return result, fmt.Errorf("a push is waiting to be uploaded, run minevcs push first")
+	}
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		return result, err
+	}
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return result, err
+	}
+	if _, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld); errors.Is(err, drive.ErrNotFound) {
+		result.Result = "skipped"
+		return result, fmt.Errorf("not on Drive yet")
+	} else if err != nil {
+		return result, err
+	}
+	if !force {
+		same, err := a.checkHashIsSame(w)
+		if err != nil {
+			return result, err
+		}
+		if same {
+			result.Result = "in sync"
+			return result, nil
+		}
+	}
+	if err := a.pullWorld(w); err != nil {
+		return result, err
+	}
+	result.Result = "pulled"
+	return result, nil
+}
+
+// CLIHistory is what minevcs history prints
+type CLIHistory struct {
+	World    string         `json:"world"`
+	Path     string         `json:"path"`
+	Versions []WorldVersion `json:"versions"`
+}
+
+func cliHistory(a *App, args []string, opts cliOptions) int {
+	if len(args) != 1 {
+		return cliFail(opts.json, exitUsage, fmt.Errorf("usage: minevcs history <world>"))
+	}
+	worlds, err := a.cliSelectWorlds(args, nil)
+	if err != nil {
+		return cliFail(opts.json, exitUsage, err)
+	}
+	w := worlds[0]
+	if err := requireSignIn(); err != nil {
+		// local copies can still be listed
+		println("Drive copies not listed:", err.Error())
+	}
+	versions, err := a.worldHistory(w)
+	if err != nil {
+		return cliFail(opts.json, errorCode(err), err)
+	}
+	history := CLIHistory{World: w.Name, Path: w.path(), Versions: versions}
+	if history.Versions == nil {
+		history.Versions = []WorldVersion{}
+	}
+	cliPrint(opts.json, history, func(out io.Writer) {
+		fmt.Fprintln(out, "ID\tSOURCE\tTIME\tMINECRAFT\tSIZE")
+		for _, v := range history.Versions {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\n", v.ID, v.Source, v.Time, v.VersionName, formatBytes(v.Size))
+		}
+	})
+	return exitOK
+}
+
+func cliRestore(a *App, args []string, opts cliOptions) int {
+	if len(args) != 2 {
+		return cliFail(opts.json, exitUsage, fmt.Errorf("usage: minevcs restore <world> <id>, the ids are listed by minevcs history"))
+	}
+	worlds, err := a.cliSelectWorlds(args[:1], nil)
+	if err != nil {
+		return cliFail(opts.json, exitUsage, err)
+	}
+	w := worlds[0]
+	if args[1] == SourceDrive {
+		if err := requireSignIn(); err != nil {
+			return cliFail(opts.json, errorCode(err), err)
+		}
+	} else {
+		unlockTokenStorage() // only needed to find the world's id on Drive, if it doesn't have one yet
+	}
+	result := CLIResult{World: w.Name, Path: w.path(), Result: "restored"}
+	err = a.restoreWorld(w, args[1])
+	if err != nil {
+		result.Result = "failed"
+		result.Error = err.Error()
+	}
+	return cliResults(opts.json, []CLIResult{result}, []error{err})
+}
+
+// CLIAuth is what minevcs auth prints
+type CLIAuth struct {
+	Auth    AuthStatus     `json:"auth"`
+	Account *drive.Account `json:"account,omitempty"`
+	Storage string         `json:"storage"`
+}
+
+func cliAuth(a *App, args []string, opts cliOptions) int {
+	if len(args) != 1 {
+		return cliFail(opts.json, exitUsage, fmt.Errorf("usage: minevcs auth status|login|logout"))
+	}
+	switch args[0] {
+	case "status":
+	case "login":
+		if err := a.cliLogin(opts.paste); err != nil {
+			return cliFail(opts.json, exitAuth, err)
+		}
+	case "logout":
+		if err := unlockTokenStorage(); err != nil {
+			return cliFail(opts.json, exitAuth, err)
+		}
+		if err := a.SignOut(); err != nil {
+			return cliFail(opts.json, exitFailed, err)
+		}
+	default:
+		return cliFail(opts.json, exitUsage, fmt.Errorf("unknown auth command %q, use status, login or logout", args[0]))
+	}
+
+	unlockErr := unlockTokenStorage()
+	result := CLIAuth{}
+	result.Storage, _ = drive.TokenStorage()
+	status, err := a.GetAuthStatus()
+	if err != nil {
+		return cliFail(opts.json, errorCode(err), err)
+	}
+	if unlockErr != nil && status.State == drive.AuthLocked {
+		status.Message = unlockErr.Error()
+	}
+	result.Auth = status
+	code := exitOK
+	switch status.State {
+	case drive.AuthOK, drive.AuthFullAccess:
+		if account, err := a.GetAccount(); err == nil {
+			result.Account = &account
+		}
+	case drive.AuthOffline:
+		code = exitFailed
+	case drive.AuthMissing:
+		if args[0] != "logout" {
+			code = exitAuth
+		}
+	default:
+		code = exitAuth
+	}
+	cliPrint(opts.json, result, func(w io.Writer) {
+		fmt.Fprintln(w, status.Message)
+		if result.Account != nil {
+			fmt.Fprintf(w, "Account:\t%s (%s)\n", result.Account.Name, result.Account.Email)
+		}
+		fmt.Fprintf(w, "Token kept in:\t%s\n", result.Storage)
+	})
+	return code
+}
+
+// signs in through the browser on this machine, or with --paste by typing in the code the redirect page
+// shows, which works when the browser is somewhere else (over SSH)
+func (a *App) cliLogin(paste bool) error {
+	if _, locked := drive.TokenStorage(); locked && os.Getenv(passphraseEnv) == "" {
+		return fmt.Errorf("there's no keyring here, set %s to the passphrase that will protect the token", passphraseEnv)
+	}
+	if err := unlockTokenStorage(); err != nil {
+		return err
+	}
+	if !paste {
+		auth, err := drive.StartLoopbackAuth()
+		if err == nil {
+			fmt.Fprintln(os.Stderr, "Open this link in a browser on this machine to sign in (use --paste if the browser is on another one):")
+			fmt.Fprintln(os.Stderr, auth.URL)
+			ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+			defer cancel()
+			return auth.Wait(ctx)
+		}
+		fmt.Fprintln(os.Stderr, "Sign in can't come back here by itself, falling back to pasting the code:", err)
+	}
+	url, err := drive.Authenticate()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Open this link in any browser, sign in, then paste the code it shows here:")
+	fmt.Fprintln(os.Stderr, url)
+	fmt.Fprint(os.Stderr, "Code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && code == "" {
+		return fmt.Errorf("no code entered")
+	}
+	_, err = drive.VerifyAuthCode(strings.TrimSpace(code))
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// the command line mode always has the terminal it was started from
+func attachConsole() {}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCLIFlags(t *testing.T) {
+	cases := []struct {
+		args       []string
+		positional []string
+		opts       cliOptions
+		err        bool
+	}{
+		{args: nil},
+		{args: []string{"MyWorld"}, positional: []string{"MyWorld"}},
+		{args: []string{"--json", "MyWorld"}, positional: []string{"MyWorld"}, opts: cliOptions{json: true}},
+		{args: []string{"MyWorld", "--json"}, positional: []string{"MyWorld"}, opts: cliOptions{json: true}},
+		{args: []string{"A", "--force", "B", "-json"}, positional: []string{"A", "B"}, opts: cliOptions{json: true, force: true}},
+		{args: []string{"--cloud", "--paste"}, opts: cliOptions{cloud: true, paste: true}},
+		// everything after -- is a world, even if it looks like a flag
+		{args: []string{"--", "--json"}, positional: []string{"--json"}},
+		{args: []string{"--nope"}, err: true},
+		{args: []string{"MyWorld", "--json=maybe"}, err: true},
+	}
+	for _, c := range cases {
+		flags := flag.NewFlagSet("minevcs test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		var opts cliOptions
+		flags.BoolVar(&opts.json, "json", false, "")
+		flags.BoolVar(&opts.force, "force", false, "")
+		flags.BoolVar(&opts.cloud, "cloud", false, "")
+		flags.BoolVar(&opts.paste, "paste", false, "")
+		positional, err := parseCLIFlags(flags, c.args)
+		if c.err {
+			if err == nil {
+				t.Errorf("%q: no error", c.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", c.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(positional, c.positional) || opts != c.opts {
+			t.Errorf("%q = %q %+v, want %q %+v", c.args, positional, opts, c.positional, c.opts)
+		}
+	}
+}
+
+func TestCLIResultsExitCode(t *testing.T) {
+	failed := CLIResult{World: "a", Result: "failed", Error: "boom"}
+	signedOut := CLIResult{World: "b", Result: "failed", Error: errNotSignedIn.Error()}
+	queued := CLIResult{World: "c", Result: "queued"}
+	pushed := CLIResult{World: "d", Result: "pushed"}
+	boom := errors.New("boom")
+	cases := []struct {
+		name    string
+		results []CLIResult
+		errs    []error
+		code    int
+	}{
+		{"nothing to do", nil, nil, exitOK},
+		{"all pushed", []CLIResult{pushed, pushed}, []error{nil, nil}, exitOK},
+		{"queued", []CLIResult{pushed, queued}, []error{nil, nil}, exitPending},
+		{"failed beats queued", []CLIResult{queued, failed}, []error{nil, boom}, exitFailed},
+		{"failed beats queued after it", []CLIResult{failed, queued}, []error{boom, nil}, exitFailed},
+		{"auth beats failed", []CLIResult{failed, signedOut}, []error{boom, errNotSignedIn}, exitAuth},
+		{"auth stays over later failures", []CLIResult{signedOut, failed, queued}, []error{errNotSignedIn, boom, nil}, exitAuth},
+	}
+	for _, c := range cases {
+		if code := cliResults(true, c.results, c.errs); code != c.code {
+			t.Errorf("%s: exit code %d, want %d", c.name, code, c.code)
+		}
+	}
+}
+
+func TestCLISelectWorlds(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	a := NewApp()
+	a.worlds = []WorldConfig{
+		{Name: "Survival", SavesDir: first, Policy: PolicySync},
+		{Name: "Survival", SavesDir: second, Policy: PolicyPull},
+		{Name: "Creative", SavesDir: first, Policy: PolicyPaused},
+	}
+	names := func(worlds []WorldConfig) []string {
+		var keys []string
+		for _, w := range worlds {
+			keys = append(keys, w.key())
+		}
+		return keys
+	}
+	cases := []struct {
+		name    string
+		args    []string
+		allowed func(WorldConfig) bool
+		want    []string
+		err     string
+	}{
+		{"every pushable world", nil, WorldConfig.canPush, []string{filepath.Join(first, "Survival")}, ""},
+		{"every pullable world", nil, WorldConfig.canPull, []string{filepath.Join(first, "Survival"), filepath.Join(second, "Survival")}, ""},
+		{"unique name", []string{"Creative"}, WorldConfig.canPush, []string{filepath.Join(first, "Creative")}, ""},
+		{"shared name", []string{"Survival"}, WorldConfig.canPush, nil, "2 synced worlds are named Survival"},
+		{"folder of a shared name", []string{filepath.Join(second, "Survival")}, WorldConfig.canPush, []string{filepath.Join(second, "Survival")}, ""},
+		{"folder with a trailing separator", []string{filepath.Join(first, "Survival") + string(filepath.Separator)}, WorldConfig.canPush, []string{filepath.Join(first, "Survival")}, ""},
+		{"unknown", []string{"Creative", "Hardcore"}, WorldConfig.canPush, nil, "Hardcore is not a synced world"},
+	}
+	for _, c := range cases {
+		selected, err := a.cliSelectWorlds(c.args, c.allowed)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: err = %v, want %q", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got := names(selected); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: selected %q, want %q", c.name, got, c.want)
+		}
+	}
+}
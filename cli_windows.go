@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// release builds are GUI programs, which start without a console, so anything printed would be lost. this
+// attaches to the console of the shell the command was typed in, leaving redirected output alone
+func attachConsole() {
+	attach := windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+	const attachParentProcess = ^uintptr(0) // (DWORD)-1
+	if ok, _, _ := attach.Call(attachParentProcess); ok == 0 {
+		return // already has a console, or wasn't started from one
+	}
+	reopen := func(std uint32, name string, flag int) *os.File {
+		if handle, err := windows.GetStdHandle(std); err == nil && handle != 0 && handle != windows.InvalidHandle {
+			return nil // redirected to a file or a pipe
+		}
+		f, err := os.OpenFile(name, flag, 0)
+		if err != nil {
+			return nil
+		}
+		return f
+	}
+	if f := reopen(windows.STD_OUTPUT_HANDLE, "CONOUT$", os.O_WRONLY); f != nil {
+		os.Stdout = f
+	}
+	if f := reopen(windows.STD_ERROR_HANDLE, "CONOUT$", os.O_WRONLY); f != nil {
+		os.Stderr = f
+	}
+	if f := reopen(windows.STD_INPUT_HANDLE, "CONIN$", os.O_RDONLY); f != nil {
+		os.Stdin = f
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"drive/nbt"
+
+	gdrive "google.golang.org/api/drive/v3"
+)
+
+// how many automatic backups are kept per world before the oldest are deleted
+const backupsToKeep = 5
+
+// versionError means a pull was blocked because this machine's Minecraft is too old for the world on Drive
+type versionError struct {
+	world string // version the world was saved with
+	game  string // version this machine runs
+}
+
+func (e *versionError) Error() string {
+	return fmt.Sprintf("the world on Drive was saved with Minecraft %s but this machine runs %s. Opening it in an older version can corrupt it, update Minecraft and try again", e.world, e.game)
+}
+
+// reads the world's DataVersion and version name from its level.dat. 0 if it can't be read or the world
+// predates 1.9
+func localWorldVersion(worldPath string) (int32, string) {
+	level, err := nbt.ReadLevel(filepath.Join(worldPath, "level.dat"))
+	if err != nil {
+		return 0, ""
+	}
+	return level.DataVersion, level.VersionName
+}
+
+// the properties stored on an uploaded world so other machines can check it before pulling
+func versionProperties(dataVersion int32, versionName string) map[string]string {
+	if dataVersion == 0 {
+		return nil
+	}
+	return map[string]string{
+		"dataVersion": strconv.Itoa(int(dataVersion)),
+		"versionName": versionName,
+	}
+}
+
+// the game directory is the folder holding the saves folder
+func (w WorldConfig) gameDir() string {
+	return filepath.Dir(savesPath(w.SavesDir))
+}
+
+// compares the world about to be pulled with the local game and the local copy. pulling a world saved by a
+// newer Minecraft than the one installed here is refused. upgrade is true when the local world will end up
+// in a newer format than it is now (either the pulled copy is newer or the game will convert it when opened),
+// so it should be backed up first
+func (a *App) checkPullVersion(w WorldConfig, zipFile *gdrive.File) (upgrade bool, err error) {
+	cloudVersion, _ := strconv.Atoi(zipFile.AppProperties["dataVersion"])
+	cloudName := zipFile.AppProperties["versionName"]
+	if cloudVersion == 0 {
+		// pushed by a version of MineVCS that didn't record it
+		println("No data version recorded for", w.Name, "on Drive, skipping version check")
+		return false, nil
+	}
+	game, err := detectGameVersion(w.gameDir())
+	if err != nil {
+		a.printAndEmit("Could not detect the local Minecraft version, make sure it can open worlds from " + cloudName + " ⚠️")
+	} else if game.DataVersion == 0 {
+		a.printAndEmit("Could not tell which worlds Minecraft " + game.Name + " can open, make sure it can open worlds from " + cloudName + " ⚠️")
+	} else if int32(cloudVersion) > game.DataVersion {
+		return false, &versionError{world: cloudName, game: game.Name}
+	} else if int32(cloudVersion) < game.DataVersion {
+		a.printAndEmit(fmt.Sprintf("%s was saved with Minecraft %s, it will be upgraded to %s when opened", w.Name, cloudName, game.Name))
+	}
+
+	localVersion, _ := localWorldVersion(w.path())
+	if localVersion == 0 {
+		return false, nil
+	}
+	return int32(cloudVersion) > localVersion || game.DataVersion > localVersion, nil
+}
+
+func backupsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".minevcs", "backups")
+}
+
+// zips the local world into ~/.minevcs/backups/<world id>/ and keeps only the newest few
+func (a *App) backupWorld(w WorldConfig) (string, error) {
+	version, name := localWorldVersion(w.path())
+	dir := filepath.Join(backupsDir(), w.ID, fmt.Sprintf("%s-%d-%s", time.Now().UTC().Format("20060102T150405Z"), version, name))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	zipPath, err := a.zipFolder(w.path(), dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	a.pruneBackups(filepath.Join(backupsDir(), w.ID))
+	return zipPath, nil
+}
+
+func (a *App) pruneBackups(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	// names start with the time so they sort oldest first
+	sort.Strings(names)
+	for len(names) > backupsToKeep {
+		os.RemoveAll(filepath.Join(dir, names[0]))
+		names = names[1:]
+	}
+}
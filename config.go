@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configVersion is the schema version written to the config file. bump it and add a step to configMigrations
+// whenever the format changes
+const configVersion = 3
+
+// Config is what gets saved to ~/.minevcs/config.json
+type Config struct {
+	Version           int              `json:"version"`
+	MinecraftLauncher string           `json:"minecraftLauncher"`
+	Worlds            []WorldConfig    `json:"worlds"`
+	Transfer          TransferSettings `json:"transfer"`
+	Profile           ProfileSettings  `json:"profile"`
+}
+
+// ConfigError is a problem with one field of the config file. Field is its path, e.g. worlds[1].savesDir
+type ConfigError struct {
+	Field string `json:"field"`
+	Err   error  `json:"-"`
+}
+
+func (e *ConfigError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func configFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".minevcs", "config.json")
+}
+
+// each step takes the raw config of version i to version i+1
+var configMigrations = []func(raw map[string]json.RawMessage) (map[string]json.RawMessage, error){
+	migrateFlatConfig,
+	migrateUnversionedConfig,
+	migrateConfigPaths,
+}
+
+// version 0 -> 1: the flat single world format written before multiple worlds could be synced
+func migrateFlatConfig(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	if _, ok := raw["worlds"]; ok {
+		return raw, nil // already has the worlds list, only the version is missing
+	}
+	legacy := map[string]string{}
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, &ConfigError{Field: key, Err: fmt.Errorf("expected a string")}
+		}
+		legacy[key] = s
+	}
+	config := Config{
+		MinecraftLauncher: legacy["minecraftLauncher"],
+		Worlds:            []WorldConfig{},
+		Transfer:          transferSettingsFromConfig(legacy),
+	}
+	if legacy["worldName"] != "" {
+		config.Worlds = []WorldConfig{{
+			Name:     legacy["worldName"],
+			SavesDir: legacy["minecraftDirectory"],
+			Policy:   PolicySync,
+		}}
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	migrated := map[string]json.RawMessage{}
+	return migrated, json.Unmarshal(data, &migrated)
+}
+
+// version 1 -> 2: drops lastUpdated, which was written on every save and never read, and gives worlds added
+// before sync policies existed the default one
+func migrateUnversionedConfig(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	delete(raw, "lastUpdated")
+	var worlds []map[string]json.RawMessage
+	if data, ok := raw["worlds"]; ok && string(data) != "null" {
+		if err := json.Unmarshal(data, &worlds); err != nil {
+			return nil, &ConfigError{Field: "worlds", Err: fmt.Errorf("expected a list of worlds")}
+		}
+	}
+	for _, w := range worlds {
+		if policy, ok := w["policy"]; !ok || string(policy) == `""` {
+			w["policy"], _ = json.Marshal(PolicySync)
+		}
+	}
+	if worlds != nil {
+		data, err := json.Marshal(worlds)
+		if err != nil {
+			return nil, err
+		}
+		raw["worlds"] = data
+	}
+	return raw, nil
+}
+
+// version 2 -> 3: folders are stored by normalizePath. they used to always be joined onto the home directory,
+// which doubled the absolute saves path some platforms defaulted to
+func migrateConfigPaths(raw map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	var worlds []map[string]json.RawMessage
+	if data, ok := raw["worlds"]; ok && string(data) != "null" {
+		if err := json.Unmarshal(data, &worlds); err != nil {
+			return nil, &ConfigError{Field: "worlds", Err: fmt.Errorf("expected a list of worlds")}
+		}
+	}
+	for i, w := range worlds {
+		if _, ok := w["savesDir"]; !ok {
+			continue // reported as missing by validate
+		}
+		var savesDir string
+		if err := json.Unmarshal(w["savesDir"], &savesDir); err != nil {
+			return nil, &ConfigError{Field: fmt.Sprintf("worlds[%d].savesDir", i), Err: fmt.Errorf("expected a folder")}
+		}
+		w["savesDir"], _ = json.Marshal(legacyPath(savesDir))
+	}
+	if worlds != nil {
+		data, err := json.Marshal(worlds)
+		if err != nil {
+			return nil, err
+		}
+		raw["worlds"] = data
+	}
+	if data, ok := raw["profile"]; ok && string(data) != "null" {
+		var profile map[string]json.RawMessage
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return nil, &ConfigError{Field: "profile", Err: fmt.Errorf("expected an object")}
+		}
+		var gameDir string
+		if value, ok := profile["gameDir"]; ok {
+			if err := json.Unmarshal(value, &gameDir); err != nil {
+				return nil, &ConfigError{Field: "profile.gameDir", Err: fmt.Errorf("expected a folder")}
+			}
+			profile["gameDir"], _ = json.Marshal(legacyPath(gameDir))
+		}
+		data, err := json.Marshal(profile)
+		if err != nil {
+			return nil, err
+		}
+		raw["profile"] = data
+	}
+	return raw, nil
+}
+
+// reads the config file, bringing it up to the current version. migrated reports whether it was written by
+// an older version and should be saved again. a missing file is an empty config
+func readConfig() (config *Config, migrated bool, err error) {
+	data, err := os.ReadFile(configFilePath())
+	if os.IsNotExist(err) {
+		return &Config{Version: configVersion}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) (*Config, bool, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, false, &ConfigError{Err: fmt.Errorf("not valid JSON at byte %d: %w", syntaxErr.Offset, err)}
+		}
+		return nil, false, &ConfigError{Err: fmt.Errorf("expected a JSON object")}
+	}
+	if raw == nil {
+		return nil, false, &ConfigError{Err: fmt.Errorf("expected a JSON object")}
+	}
+	version := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil || version < 0 {
+			return nil, false, &ConfigError{Field: "version", Err: fmt.Errorf("expected a schema version number")}
+		}
+	}
+	if version > configVersion {
+		return nil, false, &ConfigError{Field: "version", Err: fmt.Errorf("written by a newer version of MineVCS (schema %d, this one reads up to %d), update MineVCS", version, configVersion)}
+	}
+	migrated := version < configVersion
+	for ; version < configVersion; version++ {
+		var err error
+		if raw, err = configMigrations[version](raw); err != nil {
+			return nil, false, fmt.Errorf("migrating from schema %d: %w", version, err)
+		}
+	}
+	raw["version"], _ = json.Marshal(configVersion)
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false, err
+	}
+
+	config := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, false, decodeError(err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, false, err
+	}
+	return config, migrated, nil
+}
+
+// turns what encoding/json returns into an error naming the field
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &ConfigError{Field: typeErr.Field, Err: fmt.Errorf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &ConfigError{Field: strings.Trim(field, `"`), Err: fmt.Errorf("unknown setting")}
+	}
+	return &ConfigError{Err: err}
+}
+
+// checks the values that can be wrong without looking at the disk. a config that fails this is never written
+func (c *Config) validate() error {
+	seen := map[string]bool{}
+	for i, w := range c.Worlds {
+		field := fmt.Sprintf("worlds[%d]", i)
+		if w.Name == "" {
+			return &ConfigError{Field: field + ".name", Err: fmt.Errorf("missing")}
+		}
+		if w.Name != filepath.Base(w.Name) || w.Name == "." || w.Name == ".." {
+			return &ConfigError{Field: field + ".name", Err: fmt.Errorf("%q is not a world folder name", w.Name)}
+		}
+		if w.SavesDir == "" {
+			return &ConfigError{Field: field + ".savesDir", Err: fmt.Errorf("missing")}
+		}
+		if !validPolicy(w.Policy) {
+			return &ConfigError{Field: field + ".policy", Err: fmt.Errorf("unknown sync policy %q", w.Policy)}
+		}
+		if seen[w.key()] {
+			return &ConfigError{Field: field, Err: fmt.Errorf("%s is listed twice", w.Name)}
+		}
+		seen[w.key()] = true
+	}
+	if err := c.Transfer.validate(); err != nil {
+		return &ConfigError{Field: "transfer", Err: err}
+	}
+	if err := c.Profile.validate(); err != nil {
+		return &ConfigError{Field: "profile", Err: err}
+	}
+	return nil
+}
+
+// checks the config against this machine: the launcher is installed, the saves folders exist and can be
+// written to, and worlds that are only pushed from here are there. these are reported, not refused, since
+// the fix is usually outside MineVCS (a drive that isn't mounted yet, a launcher being reinstalled)
+func (c *Config) check() []*ConfigError {
+	var problems []*ConfigError
+	if c.MinecraftLauncher != "" {
+		if _, err := os.Stat(resolvePath(c.MinecraftLauncher)); err != nil {
+			problems = append(problems, &ConfigError{Field: "minecraftLauncher", Err: fmt.Errorf("%s was not found", c.MinecraftLauncher)})
+		}
+	}
+	dirErrors := map[string]error{}
+	for i, w := range c.Worlds {
+		field := fmt.Sprintf("worlds[%d]", i)
+		dir := savesPath(w.SavesDir)
+		err, checked := dirErrors[dir]
+		if !checked {
+			err = checkWritableDir(dir)
+			dirErrors[dir] = err
+			if err != nil {
+				problems = append(problems, &ConfigError{Field: field + ".savesDir", Err: err})
+			}
+		}
+		if err != nil {
+			continue
+		}
+		// a world that can be pulled doesn't have to exist yet, the first pull creates it
+		if w.Policy == PolicyPush {
+			if _, err := os.Stat(w.path()); err != nil {
+				problems = append(problems, &ConfigError{Field: field + ".name", Err: fmt.Errorf("%s was not found in %s", w.Name, dir)})
+			}
+		}
+	}
+	return problems
+}
+
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("%s was not found", dir)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	f, err := os.CreateTemp(dir, ".minevcs-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s can't be written to", dir)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// writes the config to a temporary file next to it and renames it over the old one, so a crash or a full
+// disk never leaves half a config behind
+func writeConfig(config *Config) error {
+	config.Version = configVersion
+	if config.Worlds == nil {
+		config.Worlds = []WorldConfig{}
+	}
+	if err := config.validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := configFilePath()
+	tmp, err := os.CreateTemp(filepath.Dir(path), "config-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // only still there if something failed
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writes the app's current settings to the config file
+func (a *App) saveConfig() error {
+	a.mu.Lock()
+	config := &Config{
+		MinecraftLauncher: a.minecraftLauncher,
+		Worlds:            append([]WorldConfig(nil), a.worlds...),
+		Transfer:          a.transfer,
+		Profile:           a.profile,
+	}
+	a.mu.Unlock()
+	return writeConfig(config)
+}
+
+// reports every problem the current settings have on this machine
+func (a *App) reportConfigProblems() {
+	a.mu.Lock()
+	config := &Config{
+		MinecraftLauncher: a.minecraftLauncher,
+		Worlds:            append([]WorldConfig(nil), a.worlds...),
+	}
+	a.mu.Unlock()
+	for _, problem := range config.check() {
+		a.printAndEmit("Config: " + problem.Error() + " ⚠️")
+	}
+}
+
+// keeps the file an older version wrote next to the config, then saves it in the current format
+func (a *App) saveMigratedConfig(config *Config) {
+	backup := configFilePath() + ".bak"
+	if data, err := os.ReadFile(configFilePath()); err == nil {
+		if err := os.WriteFile(backup, data, 0644); err != nil {
+			a.printAndEmit("Unable to back up the old config file, leaving it as it is: " + err.Error() + " ⚠️")
+			return
+		}
+	}
+	if err := writeConfig(config); err != nil {
+		a.printAndEmit("Unable to update the config file: " + err.Error() + " ⚠️")
+		return
+	}
+	a.printAndEmit(fmt.Sprintf("Config file updated to schema %d, the old one was kept as %s ✅", configVersion, filepath.Base(backup)))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// points the home directory at a fresh folder, which is where the config and home relative paths live
+func setTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.MkdirAll(filepath.Join(home, ".minevcs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// testdata/config holds a config as each older version wrote it, {{HOME}} stands for the home directory
+func readConfigFixture(t *testing.T, name string, home string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "config", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(strings.ReplaceAll(string(data), "{{HOME}}", filepath.ToSlash(home)))
+}
+
+func TestConfigMigrations(t *testing.T) {
+	home := setTestHome(t)
+	saves := filepath.Join("Library", "Application Support", "minecraft", "saves")
+	// a path some platforms defaulted to as an absolute one was joined onto the home directory, the v2 fixture
+	// has one and it's only recognised as doubled when the joined folder exists
+	if err := os.MkdirAll(filepath.Join(home, saves), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]Config{
+		"v0.json": {
+			Version:           configVersion,
+			MinecraftLauncher: "/Applications/Minecraft.app",
+			Worlds:            []WorldConfig{{Name: "Survival", SavesDir: saves, Policy: PolicySync}},
+			Transfer:          TransferSettings{UploadLimitKBps: 512, DeferPushesOverMB: 200, PushWindowStart: "01:00", PushWindowEnd: "06:00"},
+		},
+		"v1.json": {
+			Version:           configVersion,
+			MinecraftLauncher: "/Applications/Minecraft.app",
+			Worlds: []WorldConfig{
+				{ID: "2f8c1d7e5a9b4c03", Name: "Survival", SavesDir: saves, Policy: PolicySync},
+				{Name: "Creative", SavesDir: saves, Policy: PolicyPull},
+			},
+			Transfer: TransferSettings{UploadLimitKBps: 512},
+		},
+		"v2.json": {
+			Version:           configVersion,
+			MinecraftLauncher: "/Applications/Minecraft.app",
+			Worlds: []WorldConfig{
+				{ID: "2f8c1d7e5a9b4c03", Name: "Survival", SavesDir: saves, Policy: PolicySync},
+				{Name: "Creative", SavesDir: saves, Policy: PolicyPull},
+			},
+			Transfer: TransferSettings{UploadLimitKBps: 512},
+			Profile:  ProfileSettings{Items: []string{ProfileOptions}, GameDir: filepath.Dir(saves)},
+		},
+	}
+	for name, want := range cases {
+		config, migrated, err := parseConfig(readConfigFixture(t, name, home))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !migrated {
+			t.Errorf("%s: not reported as migrated", name)
+		}
+		if !reflect.DeepEqual(*config, want) {
+			t.Errorf("%s migrated to\n%+v\nwant\n%+v", name, *config, want)
+		}
+
+		// what the migration writes reads back as it is
+		if err := writeConfig(config); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		reread, migrated, err := readConfig()
+		if err != nil || migrated || !reflect.DeepEqual(reread, config) {
+			t.Errorf("%s read back as %+v, migrated %v, %v", name, reread, migrated, err)
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	setTestHome(t)
+	world := `{"name": "Survival", "savesDir": "saves", "policy": "sync"}`
+	cases := []struct {
+		name  string
+		data  string
+		field string
+		err   string
+	}{
+		{"not json", `{"version": 3,`, "", "not valid JSON"},
+		{"not an object", `[]`, "", "expected a JSON object"},
+		{"null", `null`, "", "expected a JSON object"},
+		{"version as a string", `{"version": "3"}`, "version", "schema version number"},
+		{"negative version", `{"version": -1}`, "version", "schema version number"},
+		{"newer version", `{"version": 4}`, "version", "newer version of MineVCS"},
+		// DisallowUnknownFields reports these by message only, decodeError has to find the name in it
+		{"unknown setting", `{"version": 3, "theme": "dark"}`, "theme", "unknown setting"},
+		{"unknown world setting", `{"version": 3, "worlds": [{"name": "A", "savesDir": "s", "policy": "sync", "colour": "red"}]}`, "colour", "unknown setting"},
+		{"worlds not a list", `{"version": 3, "worlds": {}}`, "worlds", "expected []main.WorldConfig"},
+		{"limit not a number", `{"version": 3, "transfer": {"uploadLimitKBps": "fast"}}`, "transfer.uploadLimitKBps", "expected int64, got string"},
+		{"world without a name", `{"version": 3, "worlds": [{"savesDir": "saves", "policy": "sync"}]}`, "worlds[0].name", "missing"},
+		{"world name with a path", `{"version": 3, "worlds": [{"name": "../Survival", "savesDir": "saves", "policy": "sync"}]}`, "worlds[0].name", "not a world folder name"},
+		{"world without a folder", `{"version": 3, "worlds": [{"name": "Survival", "policy": "sync"}]}`, "worlds[0].savesDir", "missing"},
+		{"unknown policy", `{"version": 3, "worlds": [{"name": "Survival", "savesDir": "saves", "policy": "sometimes"}]}`, "worlds[0].policy", `unknown sync policy "sometimes"`},
+		{"world listed twice", `{"version": 3, "worlds": [` + world + `, ` + world + `]}`, "worlds[1]", "listed twice"},
+		{"negative limit", `{"version": 3, "transfer": {"downloadLimitKBps": -1}}`, "transfer", "can't be negative"},
+		{"periodic push too often", `{"version": 3, "transfer": {"periodicPushMinutes": 2}}`, "transfer", "every 5 minutes"},
+		{"deferred without a window", `{"version": 3, "transfer": {"deferPushesOverMB": 100, "pushWindowStart": "1am"}}`, "transfer", "push window start"},
+		{"unknown profile item", `{"version": 3, "profile": {"items": ["screenshots"]}}`, "profile", `unknown profile item "screenshots"`},
+		{"bad local option", `{"version": 3, "profile": {"localOptions": ["key:value"]}}`, "profile", "not an options.txt key"},
+		// migrations report the field in the format they read
+		{"v0 value not a string", `{"worldName": 3}`, "worldName", "expected a string"},
+		{"v1 worlds not a list", `{"worlds": "Survival"}`, "worlds", "expected a list of worlds"},
+		{"v2 folder not a string", `{"version": 2, "worlds": [{"name": "Survival", "savesDir": 1, "policy": "sync"}]}`, "worlds[0].savesDir", "expected a folder"},
+		{"v2 profile not an object", `{"version": 2, "profile": []}`, "profile", "expected an object"},
+	}
+	for _, c := range cases {
+		_, _, err := parseConfig([]byte(c.data))
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("%s: err = %v, want a ConfigError", c.name, err)
+			continue
+		}
+		if configErr.Field != c.field || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: field %q, err = %v, want %q: %q", c.name, configErr.Field, err, c.field, c.err)
+		}
+	}
+}
+
+func TestWriteConfigAtomic(t *testing.T) {
+	dir := filepath.Join(setTestHome(t), ".minevcs")
+	good := &Config{Worlds: []WorldConfig{{Name: "Survival", SavesDir: "saves", Policy: PolicySync}}}
+	if err := writeConfig(good); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(configFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	leftovers := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(dir, "config-*.tmp"))
+		return matches
+	}
+
+	// an invalid config is never written
+	bad := &Config{Worlds: []WorldConfig{{Name: "Survival", SavesDir: "saves", Policy: "sometimes"}}}
+	if err := writeConfig(bad); err == nil {
+		t.Error("an invalid config was written")
+	}
+	if after, _ := os.ReadFile(configFilePath()); string(after) != string(before) {
+		t.Errorf("config changed by a rejected write:\n%s", after)
+	}
+
+	// a write that fails at the last step leaves the old file and no temporary one behind. a folder in the way of
+	// the rename stands in for the disk giving out
+	if err := os.Rename(configFilePath(), configFilePath()+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(configFilePath(), "in-the-way"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(good); err == nil {
+		t.Error("writing over a folder succeeded")
+	}
+	if left := leftovers(); len(left) != 0 {
+		t.Errorf("failed write left %v behind", left)
+	}
+
+	// and one that succeeds replaces the file whole
+	os.RemoveAll(configFilePath())
+	os.Rename(configFilePath()+".old", configFilePath())
+	good.Transfer.UploadLimitKBps = 64
+	if err := writeConfig(good); err != nil {
+		t.Fatal(err)
+	}
+	config, migrated, err := readConfig()
+	if err != nil || migrated || config.Transfer.UploadLimitKBps != 64 {
+		t.Errorf("read back %+v, migrated %v, %v", config, migrated, err)
+	}
+	if left := leftovers(); len(left) != 0 {
+		t.Errorf("write left %v behind", left)
+	}
+	if info, err := os.Stat(configFilePath()); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("config file mode %v, %v", info.Mode(), err)
+	}
+}
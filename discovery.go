@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"drive/drive"
+	"drive/nbt"
+)
+
+// icons bigger than this are left out rather than pushed through the bridge to the frontend
+const maxIconSize = 1 << 20
+
+// LocalWorld is a world found in a saves folder, shown in the setup picker
+type LocalWorld struct {
+	Name        string `json:"name"`        // folder name, what gets synced
+	DisplayName string `json:"displayName"` // name from level.dat, what Minecraft shows
+	Icon        string `json:"icon"`        // icon.png as a data url, empty if the world has none
+	Size        int64  `json:"size"`        // bytes on disk
+	LastPlayed  string `json:"lastPlayed"`
+	Version     string `json:"version"`
+	DataVersion int32  `json:"dataVersion"`
+	Synced      bool   `json:"synced"`
+	Error       string `json:"error"` // set when level.dat couldn't be read
+}
+
+// reads what the picker shows about one world folder. an unreadable level.dat doesn't hide the world, it's
+// still listed with the error so the user knows why the details are missing
+func readLocalWorld(worldPath string) LocalWorld {
+	world := LocalWorld{Name: filepath.Base(worldPath), DisplayName: filepath.Base(worldPath)}
+	if size, err := estimateArchiveSize(worldPath); err == nil {
+		world.Size = size
+	}
+	level, err := nbt.ReadLevel(filepath.Join(worldPath, "level.dat"))
+	if err != nil {
+		world.Error = err.Error()
+	} else {
+		if level.Name != "" {
+			world.DisplayName = level.Name
+		}
+		if !level.LastPlayed.IsZero() && level.LastPlayed.Unix() > 0 {
+			world.LastPlayed = level.LastPlayed.UTC().Format(time.RFC3339)
+		}
+		world.Version = level.VersionName
+		world.DataVersion = level.DataVersion
+	}
+	iconPath := filepath.Join(worldPath, "icon.png")
+	if info, err := os.Stat(iconPath); err == nil && info.Size() <= maxIconSize {
+		if data, err := os.ReadFile(iconPath); err == nil {
+			world.Icon = "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	return world
+}
+
+// DiscoverWorlds lists the worlds in a saves folder, most recently played first
+func (a *App) DiscoverWorlds(savesDir string) ([]LocalWorld, error) {
+	if savesDir == "" {
+		return nil, fmt.Errorf("saves folder is required")
+	}
+	dir := savesPath(savesDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read saves folder: %w", err)
+	}
+	a.mu.Lock()
+	synced := map[string]bool{}
+	for _, w := range a.worlds {
+		synced[w.key()] = true
+	}
+	a.mu.Unlock()
+
+	worlds := []LocalWorld{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		worldPath := filepath.Join(dir, entry.Name())
+		// every world has a level.dat, anything else in the saves folder isn't one
+		if _, err := os.Stat(filepath.Join(worldPath, "level.dat")); err != nil {
+			continue
+		}
+		world := readLocalWorld(worldPath)
+		world.Synced = synced[WorldConfig{Name: entry.Name(), SavesDir: savesDir}.key()]
+		worlds = append(worlds, world)
+	}
+	sort.SliceStable(worlds, func(i, j int) bool {
+		return worlds[i].LastPlayed > worlds[j].LastPlayed
+	})
+	return worlds, nil
+}
+
+// ListCloudWorlds lists the worlds on Drive, so a world that only exists there yet can be picked on a new machine
+func (a *App) ListCloudWorlds() ([]drive.CloudWorld, error) {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return nil, err
+	}
+	return drive.ListCloudWorlds(srv)
+}
@@ -0,0 +1,135 @@
+package drive
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// the PKCE verifier of the last URL handed out for the paste flow, needed when the code comes back
+var (
+	pasteMu       sync.Mutex
+	pasteVerifier string
+)
+
+// ErrAuthCancelled is returned by Wait when the sign in was closed before Google redirected back
+var ErrAuthCancelled = errors.New("sign in cancelled")
+
+func randomState() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func authCodeURL(config *oauth2.Config, state string, verifier string) string {
+	// 🔥 Force consent to always get a refresh_token, and always show the account chooser so nobody signs in
+	// to whichever account the browser happens to be using
+	return config.AuthCodeURL(
+		state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("prompt", "select_account consent"),
+		oauth2.S256ChallengeOption(verifier),
+	)
+}
+
+// LoopbackAuth is a sign in where Google redirects the browser back to a short lived listener on 127.0.0.1,
+// so the code never has to be copied by hand
+type LoopbackAuth struct {
+	URL      string // where to send the browser
+	config   *oauth2.Config
+	state    string
+	verifier string
+	server   *http.Server
+	done     chan error
+	once     sync.Once
+}
+
+// StartLoopbackAuth listens on a random local port and returns the sign in to open in the browser
+func StartLoopbackAuth() (*LoopbackAuth, error) {
+	config, err := oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the sign in redirect: %w", err)
+	}
+	config.RedirectURL = fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+	auth := &LoopbackAuth{
+		config:   config,
+		state:    randomState(),
+		verifier: oauth2.GenerateVerifier(),
+		done:     make(chan error, 1),
+	}
+	auth.URL = authCodeURL(config, auth.state, auth.verifier)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", auth.callback)
+	auth.server = &http.Server{Handler: mux}
+	go auth.server.Serve(listener)
+	return auth, nil
+}
+
+func (l *LoopbackAuth) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("state") != l.state {
+		// not the redirect we're waiting for, keep listening
+		http.Error(w, "This sign in link has expired, start again from MineVCS.", http.StatusBadRequest)
+		return
+	}
+	if reason := query.Get("error"); reason != "" {
+		authPage(w, "MineVCS was not connected to Google Drive ("+reason+"). You can close this tab.")
+		l.finish(fmt.Errorf("sign in was refused: %s", reason))
+		return
+	}
+	tok, err := l.config.Exchange(r.Context(), query.Get("code"), oauth2.VerifierOption(l.verifier))
+	if err != nil {
+		authPage(w, "MineVCS could not finish connecting to Google Drive, try again from the app.")
+		l.finish(fmt.Errorf("unable to verify token: %w", err))
+		return
+	}
+	if err := saveToken(tok); err != nil {
+		authPage(w, "MineVCS could not save the Google Drive sign in, try again from the app.")
+		l.finish(fmt.Errorf("unable to save token: %w", err))
+		return
+	}
+	authPage(w, "MineVCS is connected to Google Drive ✅ You can close this tab.")
+	l.finish(nil)
+}
+
+func authPage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html><html><body style=\"font-family:sans-serif;text-align:center;margin-top:20vh\"><p>%s</p></body></html>", html.EscapeString(message))
+}
+
+func (l *LoopbackAuth) finish(err error) {
+	l.once.Do(func() {
+		l.done <- err
+	})
+}
+
+// Wait blocks until the browser comes back and the token is saved, the context is done or Close is called
+func (l *LoopbackAuth) Wait(ctx context.Context) error {
+	defer func() {
+		// let the browser get its page before the listener goes away
+		go l.server.Shutdown(context.Background())
+	}()
+	select {
+	case err := <-l.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close gives up on the sign in
+func (l *LoopbackAuth) Close() {
+	l.finish(ErrAuthCancelled)
+}
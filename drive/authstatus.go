@@ -0,0 +1,170 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// what CheckAuth found out about the saved sign in
+const (
+	AuthOK      = "ok"
+	AuthMissing = "missing" // never signed in on this machine
+	AuthLocked  = "locked"  // saved in the encrypted file, waiting for the passphrase
+	AuthExpired = "expired" // the access token ran out and there's no refresh token to get another
+	AuthRevoked = "revoked" // Google refused the token, access was removed or the refresh token expired
+	AuthOffline = "offline" // Google couldn't be reached so the token couldn't be checked
+	// works, but was granted full access to the Drive by a version that asked for it
+	AuthFullAccess = "fullAccess"
+)
+
+const (
+	tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+	revokeURL    = "https://oauth2.googleapis.com/revoke"
+)
+
+// persistingSource saves every new token its source hands out, so an access token refreshed during one
+// run is still there after a restart
+type persistingSource struct {
+	base oauth2.TokenSource
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (s *persistingSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || tok.AccessToken != s.last.AccessToken {
+		if err := saveToken(tok); err != nil {
+			println("Unable to save refreshed token:", err.Error())
+		}
+		s.last = tok
+	}
+	return tok, nil
+}
+
+// the token source every Drive client uses
+func tokenSource(ctx context.Context, config *oauth2.Config, tok *oauth2.Token) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(tok, &persistingSource{base: config.TokenSource(ctx, tok), last: tok})
+}
+
+// IsAuthError reports whether err means the sign in itself is no longer any good, rather than a failed call
+func IsAuthError(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return retrieveErr.Response == nil || retrieveErr.Response.StatusCode < 500
+	}
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized
+}
+
+// CheckAuth checks the saved sign in with Google: the access token is refreshed if needed and then
+// checked against the tokeninfo endpoint, which notices access being revoked before the token runs out
+func CheckAuth(ctx context.Context) (string, error) {
+	tok, err := tokenFromStore()
+	if errors.Is(err, ErrNotFound) {
+		return AuthMissing, nil
+	}
+	if errors.Is(err, ErrPassphraseRequired) {
+		return AuthLocked, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !tok.Valid() && tok.RefreshToken == "" {
+		return AuthExpired, nil
+	}
+	config, err := oauthConfig()
+	if err != nil {
+		return "", err
+	}
+	fresh, err := tokenSource(ctx, config, tok).Token()
+	if IsAuthError(err) {
+		return AuthRevoked, nil
+	}
+	if err != nil {
+		return AuthOffline, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?access_token="+url.QueryEscape(fresh.AccessToken), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return AuthOffline, nil
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var info struct {
+			Scope string `json:"scope"`
+		}
+		json.NewDecoder(resp.Body).Decode(&info)
+		for _, scope := range strings.Fields(info.Scope) {
+			if scope == drive.DriveScope {
+				return AuthFullAccess, nil
+			}
+		}
+		return AuthOK, nil
+	case resp.StatusCode >= 500:
+		return AuthOffline, nil
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized:
+		return AuthRevoked, nil
+	}
+	return "", fmt.Errorf("unexpected answer from Google checking the sign in: %s", strings.ToLower(resp.Status))
+}
+
+// RevokeToken tells Google to drop every grant MineVCS has on this account and deletes the saved token
+func RevokeToken(ctx context.Context) error {
+	tok, err := tokenFromStore()
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	value := tok.RefreshToken
+	if value == "" {
+		value = tok.AccessToken
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(url.Values{"token": {value}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach Google to revoke access: %w", err)
+	}
+	resp.Body.Close()
+	// 400 means the token was already revoked or had expired, either way it's no good any more
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("Google refused to revoke access: %s", strings.ToLower(resp.Status))
+	}
+	return DeleteToken()
+}
+
+// DeleteToken forgets the saved sign in on this machine
+func DeleteToken() error {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	cachedToken = nil
+	if err := os.Remove(legacyTokenPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return store().delete()
+}
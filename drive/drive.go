@@ -2,14 +2,11 @@ package drive
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
 
 	_ "embed"
 
@@ -22,266 +19,207 @@ import (
 //go:embed assets/credentials.json
 var credentialsJSON []byte
 
-// built off of quickstart example from google drive api w/ go (adjusted for UX)
-func getClient(config *oauth2.Config) *http.Client {
-	// local token file should already exist by now
-	tok, err := tokenFromFile()
+// the OAuth client MineVCS signs in with. drive.file only reaches files MineVCS created itself, which is
+// everything it ever uploads
+func oauthConfig() (*oauth2.Config, error) {
+	config, err := google.ConfigFromJSON(credentialsJSON, drive.DriveFileScope)
 	if err != nil {
-		println(err)
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
 	}
-	return config.Client(context.Background(), tok)
+	return config, nil
 }
 
-func getURL(config *oauth2.Config) string {
-	// The file token.json stores the user's access and refresh tokens, and is
-	// created automatically when the authorization flow completes for the first
-	// time.
-	_, err := tokenFromFile()
+// built off of quickstart example from google drive api w/ go (adjusted for UX)
+func getClient(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
+	// the keyring (or the encrypted token file) holds the user's access and refresh tokens once the
+	// authorization flow completes for the first time
+	tok, err := tokenFromStore()
 	if err != nil {
-		// 🔥 Force consent to always get a refresh_token
-		authURL := config.AuthCodeURL(
-			"state-token",
-			oauth2.AccessTypeOffline,
-			oauth2.SetAuthURLParam("prompt", "consent"),
-		)
-		return authURL
+		return nil, fmt.Errorf("not signed in to Google Drive: %w", err)
 	}
-	return ""
+	return oauth2.NewClient(ctx, tokenSource(ctx, config, tok)), nil
+}
+
+func getURL(config *oauth2.Config) string {
+	// the code is pasted back by hand so there's no state to check, PKCE still ties it to this app
+	verifier := oauth2.GenerateVerifier()
+	pasteMu.Lock()
+	pasteVerifier = verifier
+	pasteMu.Unlock()
+	return authCodeURL(config, randomState(), verifier)
 }
 
 func VerifyAuthCode(code string) (*http.Client, error) {
-	b := credentialsJSON
-	config, err := google.ConfigFromJSON(b, drive.DriveScope)
+	config, err := oauthConfig()
 	if err != nil {
-		return nil, fmt.Errorf("Unable to parse client secret file to config: %v", err)
+		return nil, err
+	}
+	pasteMu.Lock()
+	verifier := pasteVerifier
+	pasteMu.Unlock()
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(verifier))
 	}
-	tok, err := config.Exchange(context.TODO(), code)
+	tok, err := config.Exchange(context.TODO(), code, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to verify token: %v", err)
 	}
-	tokFile := "token.json"
-	saveToken(tokFile, tok)
+	if err := saveToken(tok); err != nil {
+		return nil, fmt.Errorf("Unable to save token: %w", err)
+	}
 	return config.Client(context.Background(), tok), nil
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile() (*oauth2.Token, error) {
-	home, _ := os.UserHomeDir()
-	file := home + "/.minevcs/token.json"
-	f, err := os.Open(file)
+// BEGIN GOOGLE DRIVE API
+// every call below goes through retry() so a rate limit or a dropped connection doesn't abort a push halfway
+
+// ErrNotFound is wrapped by lookups that found nothing (as opposed to lookups that failed)
+var ErrNotFound = errors.New("not found")
+
+// creates the file under an id generated up front so retrying after an ambiguous failure
+// (e.g. the connection dropped after drive already stored the file) never makes a duplicate
+func createFile(ctx context.Context, srv *drive.Service, f *drive.File, media *os.File) (*drive.File, error) {
+	var ids *drive.GeneratedIds
+	err := retry(ctx, "generate file id", func() error {
+		var err error
+		ids, err = srv.Files.GenerateIds().Count(1).Space("drive").Context(ctx).Do()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
-
-// Saves a token to a file path.
-func saveToken(tokenFile string, token *oauth2.Token) {
-	home, _ := os.UserHomeDir()
-	// create a .minevcs folder if it doesn't exist
-	if _, err := os.Stat(home + "/.minevcs"); os.IsNotExist(err) {
-		err = os.Mkdir(home+"/.minevcs", 0700)
-		if err != nil {
-			log.Fatalf("Unable to create .minevcs folder: %v", err)
+	if len(ids.Ids) == 0 {
+		return nil, fmt.Errorf("drive returned no file id")
+	}
+	f.Id = ids.Ids[0]
+
+	var res *drive.File
+	attempt := 0
+	err = retry(ctx, "upload "+f.Name, func() error {
+		attempt++
+		if attempt > 1 {
+			// the previous attempt may have gone through even though we saw an error
+			existing, err := srv.Files.Get(f.Id).Fields("id, name, mimeType").Context(ctx).Do()
+			if err == nil {
+				res = existing
+				return nil
+			}
 		}
-	}
-	tokenSavePath := home + "/.minevcs/" + tokenFile
-	fmt.Printf("Saving token file to: %s\n", tokenSavePath)
-	f, err := os.OpenFile(tokenSavePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
-	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
-}
-
-// BEGIN GOOGLE DRIVE API
-func UploadFile(ctx context.Context, srv *drive.Service, file *os.File, parentId string) (*drive.File, error) {
-	var parents []string
-	if parentId != "" {
-		parents = []string{parentId}
-	}
-	fileName := filepath.Base(file.Name())
-	println("File name:", fileName)
-	// check if file already exists on drive if so delete it
-	existingFile, err := FindFileByName(srv, fileName)
-	if err != nil {
-		println("File not found on drive, creating new file...")
-	} else {
-		println("File already exists on drive, deleting existing file...")
-		err = DeleteFile(srv, existingFile.Id)
-		if err != nil {
-			return nil, fmt.Errorf("unable to delete existing file: %v", err)
+		call := srv.Files.Create(f).Context(ctx)
+		if media != nil {
+			if _, err := media.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			call = call.Media(UploadLimiter.Reader(media))
 		}
-		println("Deleted existing file successfully")
-	}
-	f := &drive.File{
-		Name:     fileName,
-		MimeType: "application/octet-stream",
-		Parents:  parents,
-	}
-	res, err := srv.Files.Create(f).Media(file).Do()
-	if err != nil {
-		return nil, fmt.Errorf("unable to create file: %v", err)
-	}
-	return res, nil
+		var err error
+		res, err = call.Do()
+		return err
+	})
+	return res, err
 }
 
 func DeleteFile(srv *drive.Service, fileId string) error {
-	err := srv.Files.Delete(fileId).Do()
+	attempt := 0
+	err := retry(context.Background(), "delete "+fileId, func() error {
+		attempt++
+		err := srv.Files.Delete(fileId).Do()
+		if err != nil && attempt > 1 && isNotFound(err) {
+			return nil // an earlier attempt already deleted it
+		}
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("unable to delete file: %v", err)
+		return fmt.Errorf("unable to delete file: %w", err)
 	}
 	fmt.Println("Deleted file:", fileId)
 	return nil
 }
 
-func findFolder(srv *drive.Service, name string, parentId string) (string, error) {
-	query := fmt.Sprintf("name = '%s' and mimeType = 'application/vnd.google-apps.folder' and trashed = false and 'root' in parents", name)
-	if parentId != "" {
-		query += fmt.Sprintf(" and '%s' in parents", parentId)
-	}
+func DownloadFile(ctx context.Context, srv *drive.Service, fileID, localPath string) error {
+	return retry(ctx, "download "+fileID, func() error {
+		resp, err := srv.Files.Get(fileID).Context(ctx).Download()
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
 
-	res, err := srv.Files.List().
-		Q(query).
-		Fields("files(id, name)").
-		Do()
-	if err != nil {
-		return "", err
-	}
-	if len(res.Files) > 0 {
-		return res.Files[0].Id, nil // return first match
-	}
-	return "", nil // not found
+		// truncates whatever a failed attempt left behind
+		out, err := os.Create(localPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, DownloadLimiter.Reader(resp.Body))
+		return err
+	})
 }
 
-func FindFileByName(srv *drive.Service, name string) (*drive.File, error) {
-	res, err := srv.Files.List().
-		Q(fmt.Sprintf("name = '%s' and trashed = false", name)).
-		Fields("files(id, name, mimeType)").
-		Do()
-	if err != nil {
-		return nil, err
-	}
-	if len(res.Files) == 0 {
-		return nil, fmt.Errorf("file '%s' not found", name)
-	}
-	return res.Files[0], nil
+// StorageQuota is the account's Drive storage in bytes. Limit is 0 for unlimited accounts
+type StorageQuota struct {
+	Limit int64
+	Usage int64
 }
 
-func DownloadFile(ctx context.Context, srv *drive.Service, fileID, localPath string) error {
-	resp, err := srv.Files.Get(fileID).Download()
-	if err != nil {
+func GetStorageQuota(srv *drive.Service) (*StorageQuota, error) {
+	var about *drive.About
+	err := retry(context.Background(), "get storage quota", func() error {
+		var err error
+		about, err = srv.About.Get().Fields("storageQuota").Do()
 		return err
-	}
-	defer resp.Body.Close()
-
-	out, err := os.Create(localPath)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if about.StorageQuota == nil {
+		return nil, fmt.Errorf("drive returned no storage quota")
+	}
+	return &StorageQuota{
+		Limit: about.StorageQuota.Limit,
+		Usage: about.StorageQuota.Usage,
+	}, nil
 }
 
-func GetLatestUploadTime(srv *drive.Service, worldName string) (string, error) {
-	query := fmt.Sprintf("name = '%s.zip' and 'root' in parents and trashed = false", worldName)
-	res, err := srv.Files.List().
-		Q(query).
-		Fields("files(id, name, modifiedTime)").
-		OrderBy("modifiedTime desc").
-		PageSize(1).
-		Do()
-	if err != nil {
-		return "", err
-	}
-	if len(res.Files) == 0 {
-		return "", fmt.Errorf("file '%s' not found", worldName)
-	}
-	return res.Files[0].ModifiedTime, nil
+// Account is the Google account the Drive belongs to
+type Account struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	PhotoURL string `json:"photoUrl"`
 }
 
-func UploadFolder(srv *drive.Service, filePath string, parentId string) (string, error) {
-	// make the name = the last part of the path
-	name := filePath
-	if filePath != "" {
-		parts := strings.Split(filePath, "/")
-		name = parts[len(parts)-1]
-	}
-	existingId, err := findFolder(srv, name, parentId)
-	if err != nil {
-		return "", err
-	}
-	if existingId != "" {
-		println("Folder already exists:", existingId)
-		println("Deleting Existing Folder...")
-		// folder exists delete it so we can replace it
-		err = DeleteFile(srv, existingId)
-		if err != nil {
-			return "", fmt.Errorf("unable to delete existing folder: %v", err)
-		}
-		println("Deleted existing folder successfully")
-	}
-	folder := &drive.File{
-		Name:     name,
-		MimeType: "application/vnd.google-apps.folder",
-	}
-	if parentId != "" {
-		folder.Parents = []string{parentId}
-	}
-	created, err := srv.Files.Create(folder).Fields("id").Do()
-	if err != nil {
-		return "", fmt.Errorf("unable to create folder: %v", err)
-	}
-	fmt.Println("Created folder:", name)
-	// for every file in the folder upload it to this created folder
-	entries, err := os.ReadDir(filePath)
+func GetAccount(srv *drive.Service) (*Account, error) {
+	var about *drive.About
+	err := retry(context.Background(), "get account", func() error {
+		var err error
+		about, err = srv.About.Get().Fields("user(displayName, emailAddress, photoLink)").Do()
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("unable to read folder: %v", err)
+		return nil, err
 	}
-	for _, entry := range entries {
-		println("Entry:", entry.Name())
-		if entry.IsDir() {
-			println("Recurring inside folder:", entry.Name())
-			subFolderPath := filePath + "/" + entry.Name()
-			subFolderId, err := UploadFolder(srv, subFolderPath, created.Id)
-			if err != nil {
-				return "", fmt.Errorf("unable to upload subfolder: %v", err)
-			}
-			println("Subfolder ID:", subFolderId)
-			continue
-		} else {
-			filePath := filePath + "/" + entry.Name()
-			file, err := os.Open(filePath)
-			if err != nil {
-				return "", fmt.Errorf("unable to open file: %v", err)
-			}
-			defer file.Close()
-			ctx := context.Background()
-			createdFile, err := UploadFile(ctx, srv, file, created.Id)
-			if err != nil {
-				return "", fmt.Errorf("unable to upload file: %v", err)
-			}
-			fmt.Println("Uploaded file:", createdFile.Name)
-		}
+	if about.User == nil {
+		return nil, fmt.Errorf("drive returned no user")
 	}
-	return created.Id, nil
+	return &Account{
+		Name:     about.User.DisplayName,
+		Email:    about.User.EmailAddress,
+		PhotoURL: about.User.PhotoLink,
+	}, nil
 }
 
 func InitDrive() (context.Context, *drive.Service, error) {
 	// Create Drive service
 	ctx := context.Background()
-	b := credentialsJSON
-	config, err := google.ConfigFromJSON(b, drive.DriveScope)
+	config, err := oauthConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := getClient(ctx, config)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+		return nil, nil, err
 	}
-	client := getClient(config)
 	srv, err := drive.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create Drive client: %w", err)
@@ -291,12 +229,9 @@ func InitDrive() (context.Context, *drive.Service, error) {
 }
 
 func Authenticate() (string, error) {
-	b := credentialsJSON
-	config, err := google.ConfigFromJSON(b, drive.DriveScope)
+	config, err := oauthConfig()
 	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+		return "", err
 	}
-	client := getURL(config)
-
-	return client, nil
+	return getURL(config), nil
 }
@@ -0,0 +1,72 @@
+package drive
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// the login keychain, through the security tool that ships with macOS
+type keychainStore struct{}
+
+// exit status of security when the item doesn't exist
+const errSecItemNotFound = 44
+
+func keyringStore() (tokenStore, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, errNoKeyring
+	}
+	if err := exec.Command("security", "default-keychain").Run(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoKeyring, err)
+	}
+	return keychainStore{}, nil
+}
+
+func security(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+		return nil, fmt.Errorf("no saved token: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keychain: %s", strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (keychainStore) load() ([]byte, error) {
+	out, err := security("find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (keychainStore) save(data []byte) error {
+	// the command goes in on stdin through security -i, as an argument the token would show up in ps for
+	// as long as security runs. base64 so it needs no quoting and survives being printed back by -w
+	command := fmt.Sprintf("add-generic-password -U -s %q -a %q -l %q -w %s\n", keyringService, keyringAccount, "MineVCS Google Drive sign in", base64.StdEncoding.EncodeToString(data))
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	cmd.Stderr = &stderr
+	// security -i keeps going after a failed command, so its error output is what tells
+	if err := cmd.Run(); err != nil || strings.TrimSpace(stderr.String()) != "" {
+		return fmt.Errorf("keychain: unable to save the token: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (keychainStore) delete() error {
+	_, err := security("delete-generic-password", "-s", keyringService, "-a", keyringAccount)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package drive
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// the Secret Service API over D-Bus, provided by GNOME Keyring, KWallet and KeePassXC
+const (
+	secretsName       = "org.freedesktop.secrets"
+	secretsPath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	defaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretService     = "org.freedesktop.Secret.Service"
+	secretItem        = "org.freedesktop.Secret.Item"
+	secretPrompt      = "org.freedesktop.Secret.Prompt"
+	noPrompt          = dbus.ObjectPath("/")
+	// how long an unlock prompt may stay open
+	promptTimeout = 2 * time.Minute
+)
+
+// the Secret struct of the spec (oayays)
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+type secretServiceStore struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+func keyringStore() (tokenStore, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoKeyring, err)
+	}
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	// the plain session is fine, the secret only travels over the local session bus
+	err = conn.Object(secretsName, secretsPath).Call(secretService+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoKeyring, err)
+	}
+	return &secretServiceStore{conn: conn, session: session}, nil
+}
+
+func (s *secretServiceStore) service() dbus.BusObject {
+	return s.conn.Object(secretsName, secretsPath)
+}
+
+func (s *secretServiceStore) attributes() map[string]string {
+	return map[string]string{"service": keyringService, "account": keyringAccount}
+}
+
+// shows the keyring's prompt (e.g. to unlock it with the login password) and waits for the user
+func (s *secretServiceStore) prompt(path dbus.ObjectPath) error {
+	if path == noPrompt || path == "" {
+		return nil
+	}
+	match := []dbus.MatchOption{dbus.WithMatchObjectPath(path), dbus.WithMatchInterface(secretPrompt), dbus.WithMatchMember("Completed")}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 4)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+	if err := s.conn.Object(secretsName, path).Call(secretPrompt+".Prompt", 0, "").Err; err != nil {
+		return err
+	}
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return fmt.Errorf("keyring: the prompt was dismissed")
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("keyring: no answer to the prompt")
+		}
+	}
+}
+
+func (s *secretServiceStore) unlock(paths []dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.service().Call(secretService+".Unlock", 0, paths).Store(&unlocked, &prompt); err != nil {
+		return err
+	}
+	return s.prompt(prompt)
+}
+
+func (s *secretServiceStore) items() ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	if err := s.service().Call(secretService+".SearchItems", 0, s.attributes()).Store(&unlocked, &locked); err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	if len(locked) > 0 {
+		if err := s.unlock(locked); err != nil {
+			return nil, err
+		}
+	}
+	return append(unlocked, locked...), nil
+}
+
+func (s *secretServiceStore) load() ([]byte, error) {
+	items, err := s.items()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no saved token: %w", ErrNotFound)
+	}
+	var result secret
+	if err := s.conn.Object(secretsName, items[0]).Call(secretItem+".GetSecret", 0, s.session).Store(&result); err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	return result.Value, nil
+}
+
+func (s *secretServiceStore) save(data []byte) error {
+	if err := s.unlock([]dbus.ObjectPath{defaultCollection}); err != nil {
+		return fmt.Errorf("keyring: %w", err)
+	}
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant("MineVCS Google Drive sign in"),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(s.attributes()),
+	}
+	value := secret{Session: s.session, Value: data, ContentType: "application/json"}
+	var item, prompt dbus.ObjectPath
+	err := s.conn.Object(secretsName, defaultCollection).Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, value, true).Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("keyring: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+func (s *secretServiceStore) delete() error {
+	items, err := s.items()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := s.conn.Object(secretsName, item).Call(secretItem+".Delete", 0).Store(&prompt); err != nil {
+			return fmt.Errorf("keyring: %w", err)
+		}
+		if err := s.prompt(prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package drive
+
+func keyringStore() (tokenStore, error) {
+	return nil, errNoKeyring
+}
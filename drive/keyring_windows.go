@@ -0,0 +1,94 @@
+package drive
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows Credential Manager. x/sys/windows doesn't wrap the Cred* functions so they're called directly
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+// CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+type credentialStore struct{}
+
+func keyringStore() (tokenStore, error) {
+	if err := procCredReadW.Find(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoKeyring, err)
+	}
+	return credentialStore{}, nil
+}
+
+func credentialTarget() *uint16 {
+	target, _ := windows.UTF16PtrFromString(keyringService + "/" + keyringAccount)
+	return target
+}
+
+func (credentialStore) load() ([]byte, error) {
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(credentialTarget())), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return nil, fmt.Errorf("no saved token: %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("credential manager: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return append([]byte(nil), unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)...), nil
+}
+
+func (credentialStore) save(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("credential manager: empty token")
+	}
+	user, _ := windows.UTF16PtrFromString(keyringAccount)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         credentialTarget(),
+		CredentialBlobSize: uint32(len(data)),
+		CredentialBlob:     &data[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("credential manager: %w", err)
+	}
+	return nil
+}
+
+func (credentialStore) delete() error {
+	ret, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(credentialTarget())), credTypeGeneric, 0)
+	if ret == 0 && !errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return fmt.Errorf("credential manager: %w", err)
+	}
+	return nil
+}
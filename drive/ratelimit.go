@@ -0,0 +1,99 @@
+package drive
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every transfer going in one direction, so two uploads running at
+// once still stay under the configured rate. a rate of 0 means unlimited
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   int64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// UploadLimiter and DownloadLimiter throttle every transfer made through this package
+var (
+	UploadLimiter   = NewRateLimiter(0)
+	DownloadLimiter = NewRateLimiter(0)
+)
+
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	return &RateLimiter{rate: bytesPerSecond, last: time.Now()}
+}
+
+func (l *RateLimiter) SetRate(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = bytesPerSecond
+	l.tokens = 0
+	l.last = time.Now()
+}
+
+func (l *RateLimiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// blocks until n bytes may pass
+func (l *RateLimiter) wait(n int) {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.rate)
+	// allow at most one second worth of burst
+	if l.tokens > float64(l.rate) {
+		l.tokens = float64(l.rate)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var sleep time.Duration
+	if l.tokens < 0 {
+		sleep = time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	}
+	l.mu.Unlock()
+	time.Sleep(sleep)
+}
+
+// chunk size for a read, small enough that a low limit still produces a smooth rate
+func (l *RateLimiter) chunk(size int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return size
+	}
+	max := int(l.rate / 10)
+	if max < 1024 {
+		max = 1024
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
+
+// Reader wraps r so everything read through it counts against the limiter
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	return &RateLimitedReader{Reader: r, Limiter: l}
+}
+
+type RateLimitedReader struct {
+	io.Reader
+	Limiter *RateLimiter
+}
+
+func (r *RateLimitedReader) Read(p []byte) (int, error) {
+	p = p[:r.Limiter.chunk(len(p))]
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.Limiter.wait(n)
+	}
+	return n, err
+}
@@ -0,0 +1,182 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy controls how every Drive call is retried on rate limits, server errors and network blips
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy gives a call roughly a minute to succeed before giving up
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 7,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// a Retry-After longer than this is treated as "not happening soon" and the call fails instead
+const maxRetryAfter = 5 * time.Minute
+
+// RetryError is returned once a call has used up all of its attempts
+type RetryError struct {
+	Op       string
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s failed after %d attempts: %v", e.Op, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// runs fn until it succeeds, returns a non retryable error or the policy runs out of attempts
+func retry(ctx context.Context, op string, fn func() error) error {
+	return DefaultRetryPolicy.do(ctx, op, fn)
+}
+
+func (p RetryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		retryable, retryAfter := classifyError(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return &RetryError{Op: op, Attempts: attempt, Err: err}
+		}
+		delay := p.backoff(attempt)
+		if retryAfter > 0 {
+			if retryAfter > maxRetryAfter {
+				return &RetryError{Op: op, Attempts: attempt, Err: err}
+			}
+			delay = retryAfter
+		}
+		fmt.Printf("%s failed (attempt %d/%d), retrying in %s: %v\n", op, attempt, p.MaxAttempts, delay.Round(time.Millisecond), err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// exponential backoff with full jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + p.BaseDelay/2
+}
+
+// reports whether err is worth retrying and, if the server told us, how long to wait first
+func classifyError(err error) (bool, time.Duration) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, parseRetryAfter(apiErr.Header)
+		case http.StatusForbidden:
+			// drive reports per-user rate limits as 403s
+			for _, item := range apiErr.Errors {
+				if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+					return true, parseRetryAfter(apiErr.Header)
+				}
+			}
+		}
+		return false, 0
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		// the token endpoint being down is transient, a rejected refresh token is not
+		if retrieveErr.Response != nil && retrieveErr.Response.StatusCode >= 500 {
+			return true, parseRetryAfter(retrieveErr.Response.Header)
+		}
+		return false, 0
+	}
+
+	// any DNS failure is retried: "no such host" is also what a resolver says while the network comes back up
+	// (wifi reconnecting, a laptop waking up). a host that really doesn't exist still fails once the attempts run
+	// out
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true, 0
+	}
+	// every *url.Error is a net.Error, so only a timeout counts. a bad certificate fails the same way on every
+	// attempt
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, 0
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EPIPE) {
+		return true, 0
+	}
+	return false, 0
+}
+
+// Retry-After is either a number of seconds or an http date
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// Reachable is a quick check that the Drive API can be reached at all, so a push made while offline is
+// queued right away instead of waiting out every retry first
+func Reachable() bool {
+	conn, err := net.DialTimeout("tcp", "www.googleapis.com:443", 5*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
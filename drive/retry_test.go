@@ -0,0 +1,148 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// a response drive sends with a status other than 200
+type apiReply struct {
+	status int
+	reason string
+}
+
+// answers each request with the next step: an error as if the request never got out, a status code or an
+// apiReply. the last step repeats
+type fakeTransport struct {
+	steps []any
+	calls int
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	step := f.steps[len(f.steps)-1]
+	if f.calls < len(f.steps) {
+		step = f.steps[f.calls]
+	}
+	f.calls++
+	switch step := step.(type) {
+	case error:
+		return nil, step
+	case int:
+		return f.reply(req, apiReply{step, reasons[step]}), nil
+	case apiReply:
+		return f.reply(req, step), nil
+	}
+	panic("bad step")
+}
+
+func (f *fakeTransport) reply(req *http.Request, reply apiReply) *http.Response {
+	body := `{"id": "abc", "name": "world.zip"}`
+	if reply.status != http.StatusOK {
+		body = fmt.Sprintf(`{"error": {"code": %d, "message": "fake", "errors": [{"reason": "%s"}]}}`, reply.status, reply.reason)
+	}
+	return &http.Response{
+		StatusCode: reply.status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// the reason drive gives with each status
+var reasons = map[int]string{
+	http.StatusNotFound:            "notFound",
+	http.StatusTooManyRequests:     "rateLimitExceeded",
+	http.StatusInternalServerError: "backendError",
+	http.StatusServiceUnavailable:  "backendError",
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func TestRetry(t *testing.T) {
+	noSuchHost := &net.DNSError{Err: "no such host", Name: "www.googleapis.com", IsNotFound: true}
+	dnsTimeout := &net.DNSError{Err: "i/o timeout", Name: "www.googleapis.com", IsTimeout: true}
+	rateLimited := apiReply{http.StatusForbidden, "rateLimitExceeded"}
+	userRateLimited := apiReply{http.StatusForbidden, "userRateLimitExceeded"}
+	forbidden := apiReply{http.StatusForbidden, "insufficientPermissions"}
+	cases := []struct {
+		name      string
+		steps     []any
+		calls     int
+		exhausted bool // failed with a RetryError after every attempt
+		ok        bool
+	}{
+		{"ok", []any{200}, 1, false, true},
+		{"no such host while the network comes back", []any{noSuchHost, noSuchHost, 200}, 3, false, true},
+		{"dns timeout", []any{dnsTimeout, 200}, 2, false, true},
+		{"no such host for good", []any{noSuchHost}, 4, true, false},
+		{"connection reset", []any{fmt.Errorf("read: %w", syscall.ECONNRESET), 200}, 2, false, true},
+		{"server errors", []any{500, 503, 200}, 3, false, true},
+		{"server errors throughout", []any{503}, 4, true, false},
+		{"too many requests", []any{429, 200}, 2, false, true},
+		{"rate limited as a 403", []any{rateLimited, userRateLimited, 200}, 3, false, true},
+		{"forbidden", []any{forbidden, 200}, 1, false, false},
+		{"not found", []any{404, 200}, 1, false, false},
+		{"bad request", []any{400, 200}, 1, false, false},
+		{"bad certificate", []any{errors.New("x509: certificate signed by unknown authority"), 200}, 1, false, false},
+	}
+	for _, c := range cases {
+		transport := &fakeTransport{steps: c.steps}
+		srv, err := drive.NewService(context.Background(), option.WithHTTPClient(&http.Client{Transport: transport}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = testPolicy.do(context.Background(), c.name, func() error {
+			_, err := srv.Files.Get("abc").Do()
+			return err
+		})
+		if transport.calls != c.calls {
+			t.Errorf("%s: %d calls, want %d", c.name, transport.calls, c.calls)
+		}
+		var retryErr *RetryError
+		if exhausted := errors.As(err, &retryErr); exhausted != c.exhausted {
+			t.Errorf("%s: err = %v, exhausted %v, want %v", c.name, err, exhausted, c.exhausted)
+		}
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v", c.name, err)
+		}
+	}
+}
+
+func TestRetryNotFound(t *testing.T) {
+	transport := &fakeTransport{steps: []any{404}}
+	srv, err := drive.NewService(context.Background(), option.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testPolicy.do(context.Background(), "get", func() error {
+		_, err := srv.Files.Get("abc").Do()
+		return err
+	})
+	if !isNotFound(err) {
+		t.Errorf("err = %v, want a 404", err)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Hour, MaxDelay: time.Hour}.do(ctx, "cancelled", func() error {
+		calls++
+		cancel()
+		return &net.DNSError{IsNotFound: true}
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
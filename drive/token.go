@@ -0,0 +1,298 @@
+package drive
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// where the token is kept
+const (
+	StorageKeyring   = "keyring"
+	StorageEncrypted = "encrypted file"
+)
+
+// the keyring entry holding the token
+const (
+	keyringService = "MineVCS"
+	keyringAccount = "google-drive-token"
+)
+
+// errNoKeyring means this machine has no secret store MineVCS can use, the token goes in an encrypted file
+var errNoKeyring = errors.New("no keyring available")
+
+// ErrPassphraseRequired means the token is in an encrypted file and SetPassphrase hasn't been called yet
+var ErrPassphraseRequired = errors.New("enter your passphrase to unlock the saved Google sign in")
+
+// ErrWrongPassphrase means the encrypted token file couldn't be opened with the passphrase given
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+
+// a place to keep the token. load returns ErrNotFound when nothing is stored
+type tokenStore interface {
+	load() ([]byte, error)
+	save(data []byte) error
+	delete() error
+}
+
+var (
+	tokenMu     sync.Mutex
+	activeStore tokenStore // picked on first use
+	storageName string
+	cachedToken *oauth2.Token
+	passphrase  string
+)
+
+func minevcsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".minevcs")
+}
+
+// token.json written by older versions, moved into the store the first time the token is read
+func legacyTokenPath() string {
+	return filepath.Join(minevcsDir(), "token.json")
+}
+
+// picks the keyring if this machine has one and the encrypted file otherwise. called with tokenMu held
+func store() tokenStore {
+	if activeStore != nil {
+		return activeStore
+	}
+	keyring, err := keyringStore()
+	if err == nil {
+		activeStore, storageName = keyring, StorageKeyring
+	} else {
+		println("Keeping the token in an encrypted file:", err.Error())
+		activeStore, storageName = &encryptedFileStore{path: filepath.Join(minevcsDir(), "token.enc")}, StorageEncrypted
+	}
+	return activeStore
+}
+
+// TokenStorage reports where the token is kept and whether a passphrase has to be entered before it can be
+// read or saved
+func TokenStorage() (string, bool) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	_, encrypted := store().(*encryptedFileStore)
+	return storageName, encrypted && passphrase == ""
+}
+
+// SetPassphrase sets the passphrase of the encrypted token file. if there already is one it has to open it
+func SetPassphrase(p string) error {
+	if p == "" {
+		return fmt.Errorf("the passphrase can't be empty")
+	}
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	previous := passphrase
+	passphrase = p
+	if _, err := loadToken(); err != nil && !errors.Is(err, ErrNotFound) {
+		passphrase = previous
+		return err
+	}
+	return nil
+}
+
+// loadToken reads the token from the store, moving a legacy token.json into it first. called with tokenMu held
+func loadToken() (*oauth2.Token, error) {
+	if cachedToken != nil {
+		return cachedToken, nil
+	}
+	if err := migrateLegacyToken(); err != nil {
+		println("Token migration postponed:", err.Error())
+		if legacy, legacyErr := readTokenFile(legacyTokenPath()); legacyErr == nil {
+			return legacy, nil
+		}
+	}
+	data, err := store().load()
+	if err != nil {
+		return nil, err
+	}
+	tok := &oauth2.Token{}
+	if err := json.Unmarshal(data, tok); err != nil {
+		return nil, fmt.Errorf("saved token is unreadable: %w", err)
+	}
+	cachedToken = tok
+	return tok, nil
+}
+
+func readTokenFile(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tok := &oauth2.Token{}
+	return tok, json.Unmarshal(data, tok)
+}
+
+// moves token.json into the store and deletes it. called with tokenMu held
+func migrateLegacyToken() error {
+	tok, err := readTokenFile(legacyTokenPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := storeToken(tok); err != nil {
+		return err
+	}
+	println("Moved token.json into the", storageName)
+	return os.Remove(legacyTokenPath())
+}
+
+// called with tokenMu held
+func storeToken(tok *oauth2.Token) error {
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	if err := store().save(data); err != nil {
+		return err
+	}
+	cachedToken = tok
+	return nil
+}
+
+// saveToken keeps the token in the keyring (or the encrypted file)
+func saveToken(tok *oauth2.Token) error {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	return storeToken(tok)
+}
+
+// tokenFromStore returns the saved token, ErrNotFound if there is none
+func tokenFromStore() (*oauth2.Token, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+	return loadToken()
+}
+
+// HasToken reports whether a token is saved. false with ErrPassphraseRequired if it can't be read yet
+func HasToken() (bool, error) {
+	_, err := tokenFromStore()
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// the encrypted file used when there's no keyring. the key is derived from the passphrase with PBKDF2 and
+// the token sealed with AES-GCM
+type encryptedFileStore struct {
+	path string
+}
+
+type encryptedToken struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+const passphraseIterations = 600000
+
+func (s *encryptedFileStore) load() ([]byte, error) {
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved token: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	var file encryptedToken
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("encrypted token file is corrupted: %w", err)
+	}
+	aead, err := tokenCipher(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return data, nil
+}
+
+func (s *encryptedFileStore) save(data []byte) error {
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+	file := encryptedToken{Version: 1, Iterations: passphraseIterations, Salt: make([]byte, 16)}
+	rand.Read(file.Salt)
+	aead, err := tokenCipher(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	rand.Read(file.Nonce)
+	file.Data = aead.Seal(nil, file.Nonce, data, nil)
+	raw, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *encryptedFileStore) delete() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func tokenCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("encrypted token file is corrupted")
+	}
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// PBKDF2 with HMAC-SHA256 (RFC 8018), small enough not to pull in another dependency for it
+func pbkdf2SHA256(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
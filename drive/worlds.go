@@ -0,0 +1,296 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// everything MineVCS uploads lives in MineVCS/<world id>/ and is tagged with appProperties, so lookups never
+// depend on file names and can't pick up unrelated files that happen to share one
+
+const (
+	AppFolderName  = "MineVCS"
+	folderMimeType = "application/vnd.google-apps.folder"
+
+	// the kinds of objects MineVCS keeps on Drive
+	KindRoot        = "root"
+	KindWorldFolder = "worldFolder"
+	KindWorld       = "world"    // the zipped world
+	KindLevelDat    = "levelDat" // copy of level.dat used for hashing
+	KindLock        = "lock"     // present while a push is in progress
+
+	// ProfileID is the id of the folder holding the game settings and packs synced by profile sync. it
+	// sits next to the world folders but isn't a world
+	ProfileID = "profile"
+)
+
+// file names used inside a world folder. only for humans browsing their Drive, lookups go by kind
+var kindNames = map[string]string{
+	KindWorld:    "world.zip",
+	KindLevelDat: "level.dat",
+	KindLock:     "temp.lock",
+}
+
+// name a file of the given kind gets on Drive. profile items are stored under their own kind
+func fileName(kind string) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return kind + ".zip"
+}
+
+// CloudWorld is a synced world as found on Drive
+type CloudWorld struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	FolderID     string `json:"folderId"`
+	ModifiedTime string `json:"modifiedTime"`
+}
+
+// quotes a value for use inside a Drive query string
+func escapeQuery(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `'`, `\'`)
+}
+
+func propertyClause(key string, value string) string {
+	return fmt.Sprintf("appProperties has { key='%s' and value='%s' }", key, escapeQuery(value))
+}
+
+func listFiles(srv *drive.Service, op string, query string, fields string, orderBy string) ([]*drive.File, error) {
+	var files []*drive.File
+	pageToken := ""
+	for {
+		var res *drive.FileList
+		err := retry(context.Background(), op, func() error {
+			call := srv.Files.List().Q(query).Fields(googleapi.Field("nextPageToken, files(" + fields + ")")).PageToken(pageToken)
+			if orderBy != "" {
+				call = call.OrderBy(orderBy)
+			}
+			var err error
+			res, err = call.Do()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, res.Files...)
+		if res.NextPageToken == "" {
+			return files, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// EnsureAppFolder returns the id of the MineVCS folder, creating it on first use
+func EnsureAppFolder(srv *drive.Service) (string, error) {
+	files, err := listFiles(srv, "find app folder",
+		propertyClause("minevcs", KindRoot)+" and trashed = false", "id", "createdTime")
+	if err != nil {
+		return "", err
+	}
+	if len(files) > 0 {
+		return files[0].Id, nil
+	}
+	folder, err := createFile(context.Background(), srv, &drive.File{
+		Name:          AppFolderName,
+		MimeType:      folderMimeType,
+		AppProperties: map[string]string{"minevcs": KindRoot},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create %s folder: %w", AppFolderName, err)
+	}
+	return folder.Id, nil
+}
+
+// FindWorldFolder returns the folder holding the world with the given id, or ErrNotFound
+func FindWorldFolder(srv *drive.Service, worldID string) (*drive.File, error) {
+	files, err := listFiles(srv, "find world folder",
+		propertyClause("kind", KindWorldFolder)+" and "+propertyClause("worldId", worldID)+" and trashed = false",
+		"id, name, appProperties, modifiedTime", "createdTime")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("world %s %w", worldID, ErrNotFound)
+	}
+	return files[0], nil
+}
+
+// EnsureWorldFolder returns the id of MineVCS/<worldID>, creating it if needed
+func EnsureWorldFolder(srv *drive.Service, worldID string, worldName string) (string, error) {
+	folder, err := FindWorldFolder(srv, worldID)
+	if err == nil {
+		return folder.Id, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	rootID, err := EnsureAppFolder(srv)
+	if err != nil {
+		return "", err
+	}
+	created, err := createFile(context.Background(), srv, &drive.File{
+		Name:        worldID,
+		MimeType:    folderMimeType,
+		Parents:     []string{rootID},
+		Description: worldName,
+		AppProperties: map[string]string{
+			"minevcs":   "1",
+			"kind":      KindWorldFolder,
+			"worldId":   worldID,
+			"worldName": worldName,
+		},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create world folder: %w", err)
+	}
+	return created.Id, nil
+}
+
+// ListCloudWorlds returns every world MineVCS has uploaded, newest first
+func ListCloudWorlds(srv *drive.Service) ([]CloudWorld, error) {
+	files, err := listFiles(srv, "list cloud worlds",
+		propertyClause("kind", KindWorldFolder)+" and trashed = false",
+		"id, appProperties, modifiedTime", "modifiedTime desc")
+	if err != nil {
+		return nil, err
+	}
+	worlds := make([]CloudWorld, 0, len(files))
+	for _, f := range files {
+		if f.AppProperties["worldId"] == ProfileID {
+			continue
+		}
+		worlds = append(worlds, CloudWorld{
+			ID:           f.AppProperties["worldId"],
+			Name:         f.AppProperties["worldName"],
+			FolderID:     f.Id,
+			ModifiedTime: f.ModifiedTime,
+		})
+	}
+	return worlds, nil
+}
+
+// FindWorldFile returns the newest object of the given kind for a world, or ErrNotFound
+func FindWorldFile(srv *drive.Service, worldID string, kind string) (*drive.File, error) {
+	files, err := listFiles(srv, "find "+kind+" of "+worldID,
+		propertyClause("kind", kind)+" and "+propertyClause("worldId", worldID)+" and trashed = false",
+		"id, name, size, modifiedTime, appProperties", "modifiedTime desc")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s of world %s %w", kind, worldID, ErrNotFound)
+	}
+	return files[0], nil
+}
+
+// UploadWorldFile uploads file as the world's object of the given kind. any previous objects of that kind
+// are only deleted once the new one is safely stored
+func UploadWorldFile(ctx context.Context, srv *drive.Service, worldID string, worldName string, kind string, file *os.File, properties map[string]string) (*drive.File, error) {
+	folderID, err := EnsureWorldFolder(srv, worldID, worldName)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := listFiles(srv, "find "+kind+" of "+worldID,
+		propertyClause("kind", kind)+" and "+propertyClause("worldId", worldID)+" and trashed = false", "id", "")
+	if err != nil {
+		return nil, err
+	}
+	appProperties := map[string]string{
+		"minevcs": "1",
+		"kind":    kind,
+		"worldId": worldID,
+	}
+	for k, v := range properties {
+		appProperties[k] = v
+	}
+	created, err := createFile(ctx, srv, &drive.File{
+		Name:          fileName(kind),
+		MimeType:      "application/octet-stream",
+		Parents:       []string{folderID},
+		AppProperties: appProperties,
+	}, file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to upload %s: %w", fileName(kind), err)
+	}
+	for _, old := range existing {
+		if err := DeleteFile(srv, old.Id); err != nil {
+			return nil, fmt.Errorf("unable to delete previous %s: %w", fileName(kind), err)
+		}
+	}
+	return created, nil
+}
+
+// GetAppUsage adds up the size of everything MineVCS has stored on Drive
+func GetAppUsage(srv *drive.Service) (int64, error) {
+	files, err := listFiles(srv, "list app files", propertyClause("minevcs", "1")+" and trashed = false", "size", "")
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, nil
+}
+
+// FindLegacyFile finds a file uploaded by versions that kept everything in the root of the Drive by name
+func FindLegacyFile(srv *drive.Service, name string) (*drive.File, error) {
+	files, err := listFiles(srv, "find legacy "+name,
+		fmt.Sprintf("name = '%s' and 'root' in parents and trashed = false", escapeQuery(name)),
+		"id, name, parents", "modifiedTime desc")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("file '%s' %w", name, ErrNotFound)
+	}
+	return files[0], nil
+}
+
+// CountLegacyZips counts the zipped worlds old versions left in the root of the Drive
+func CountLegacyZips(srv *drive.Service) (int, error) {
+	files, err := listFiles(srv, "list legacy worlds", "'root' in parents and trashed = false", "name", "")
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".zip") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// AdoptLegacyFile moves a file uploaded under the old naming into the world's folder and tags it. "legacy" marks it
+// as uploaded by a version that didn't write a manifest into the archive
+func AdoptLegacyFile(srv *drive.Service, file *drive.File, worldID string, worldName string, kind string) error {
+	folderID, err := EnsureWorldFolder(srv, worldID, worldName)
+	if err != nil {
+		return err
+	}
+	update := &drive.File{
+		Name: fileName(kind),
+		AppProperties: map[string]string{
+			"minevcs": "1",
+			"kind":    kind,
+			"worldId": worldID,
+			"legacy":  "1",
+		},
+	}
+	return retry(context.Background(), "migrate "+file.Name, func() error {
+		_, err := srv.Files.Update(file.Id, update).
+			AddParents(folderID).
+			RemoveParents(strings.Join(file.Parents, ",")).
+			Do()
+		return err
+	})
+}
@@ -1,38 +1,54 @@
 import {useState, useEffect} from 'react';
 import './App.css';
-import {GoogleAuth, UserAuthCode, CheckIfAuthenticated, SaveUserData, GetUserData, PushIfAhead, GetDefaultPaths} from "../wailsjs/go/main/App";
+import {GoogleAuth, GoogleAuthPaste, GetTokenStorage, UserAuthCode, CheckIfAuthenticated, SaveUserData, GetUserData, PushIfAhead, GetDefaultPaths, GetSyncStatus, GetQueuedPushes, GetStorageInfo, ListWorlds} from "../wailsjs/go/main/App";
+import {main} from "../wailsjs/go/models";
 import { CircleHelp, Settings, Info } from 'lucide-react';
 import {BrowserOpenURL, EventsOn} from "../wailsjs/runtime";
 import {Link} from "react-router-dom";
 import SaveTooltip from './components/SaveTooltip';
 import LaunchTooltip from './components/LaunchTooltip';
 import Logs from './components/Logs';
+import QueuedPushes from './components/QueuedPushes';
+import StorageUsage from './components/StorageUsage';
+import TransferSettings from './components/TransferSettings';
+import Worlds from './components/Worlds';
+import WorldPicker from './components/WorldPicker';
+import ProfileSync from './components/ProfileSync';
+import InstancePicker from './components/InstancePicker';
+import PlayButton from './components/PlayButton';
+import PassphrasePrompt from './components/PassphrasePrompt';
+import AuthBanner from './components/AuthBanner';
+import Account from './components/Account';
 
 function Home() {
     const [minecraftSavePath, setMinecraftSavePath] = useState<string>('');
     const [minecraftLauncherPath, setMinecraftLauncherPath] = useState<string>('');
     const [authError, setAuthError] = useState<string | null>(null);
     const [worldName, setWorldName] = useState<string>('');
+    // which world on Drive the picked world is, '' to work it out on the first sync, null while the user still has to choose
+    const [worldId, setWorldId] = useState<string | null>('');
     const [showCode, setShowCode] = useState<boolean>(false);
     const [userCode, setUserCode] = useState<string>('');
     const [showTooltip, setShowTooltip] = useState<string | null>(null);
     const [isAuthenticated, setIsAuthenticated] = useState<boolean>(false);
     const [logs, setLogs] = useState<string[]>([]);
+    const [syncStatus, setSyncStatus] = useState<main.SyncStatus | null>(null);
+    const [queuedPushes, setQueuedPushes] = useState<main.OutboxEntry[]>([]);
+    const [storage, setStorage] = useState<main.StorageInfo | null>(null);
+    const [worlds, setWorlds] = useState<main.WorldStatus[]>([]);
+    const [needsPassphrase, setNeedsPassphrase] = useState<boolean>(false);
     
     const [defaultMinecraftLauncherPath, setDefaultMinecraftLauncherPath] = useState<string>('');
     const [defaultMinecraftSavePath, setDefaultMinecraftSavePath] = useState<string>('');
      
     useEffect(() => {
-      CheckIfAuthenticated().then((isAuth: boolean) => {
-        setIsAuthenticated(isAuth);
-      });
+      checkAuth();
 
       // wait for go backend to be ready
       const offUserData = EventsOn("userDataReady", () => {
         GetUserData().then((data) => {
           setMinecraftLauncherPath(data.minecraftLauncher);
-          setMinecraftSavePath(data.minecraftDirectory);
-          setWorldName(data.worldName);
+          setWorlds(data.worlds ?? []);
           PushIfAhead().catch((error) => {
             console.error("Error pushing if ahead", error);
           });
@@ -45,16 +61,58 @@ function Home() {
         setDefaultMinecraftSavePath(data.minecraftSavePath);
       });
 
+      // the browser came back to the local sign in listener
+      const offAuthenticated = EventsOn("authenticated", () => {
+        setShowCode(false);
+        setAuthError(null);
+        setIsAuthenticated(true);
+      });
+      const offAuthError = EventsOn("authError", (msg) => {
+        setAuthError(msg as string);
+      });
+      const offSignedOut = EventsOn("signedOut", () => {
+        setIsAuthenticated(false);
+        setStorage(null);
+      });
+
       const offLog = EventsOn("log", (msg) => {
         setLogs((prev) => [...prev.slice(-199), msg as string]);
       });
+
+      GetSyncStatus().then(setSyncStatus);
+      const offSyncStatus = EventsOn("syncStatus", (status) => {
+        setSyncStatus(status as main.SyncStatus);
+        refreshStorage();
+      });
+
+      const offWorlds = EventsOn("worlds", (data) => {
+        setWorlds((data ?? []) as main.WorldStatus[]);
+      });
+
+      GetQueuedPushes().then((entries) => setQueuedPushes(entries ?? []));
+      const offOutbox = EventsOn("outbox", (entries) => {
+        setQueuedPushes((entries ?? []) as main.OutboxEntry[]);
+      });
     
       return () => {
         offUserData();
+        offAuthenticated();
+        offAuthError();
+        offSignedOut();
         offLog();
+        offSyncStatus();
+        offOutbox();
+        offWorlds();
       };
     }, []);
 
+    const checkAuth = () => {
+      GetTokenStorage().then((storage) => setNeedsPassphrase(storage.needsPassphrase));
+      CheckIfAuthenticated().then((isAuth: boolean) => {
+        setIsAuthenticated(isAuth);
+      }).catch(() => setIsAuthenticated(false));
+    }
+
     useEffect(() => {
       const logsElement = document.getElementById('logs');
       if (logsElement) {
@@ -62,6 +120,16 @@ function Home() {
       }
     }, [logs]);
 
+    const refreshStorage = () => {
+      GetStorageInfo().then(setStorage).catch((error) => {
+        console.error("Error getting storage info", error);
+      });
+    }
+
+    useEffect(() => {
+      if (isAuthenticated) refreshStorage();
+    }, [isAuthenticated]);
+
     const handleAuth = () => {
       GoogleAuth().then((url: string) => {
         BrowserOpenURL(url);
@@ -69,6 +137,14 @@ function Home() {
       })
     }
 
+    // fallback for when the browser can't get back to MineVCS, the code is shown by the redirect site instead
+    const handlePasteAuth = () => {
+      GoogleAuthPaste().then((url: string) => {
+        if (url) BrowserOpenURL(url);
+        setShowCode(true);
+      })
+    }
+
     const verifyCode = () => {
       if (userCode !== null && userCode.length > 0) {
         UserAuthCode(userCode)
@@ -90,9 +166,12 @@ function Home() {
 
     const saveUserSettings = (e: any) => {
         e.preventDefault();
-        if (!minecraftSavePath || !worldName || !minecraftLauncherPath) return;
-        SaveUserData(minecraftLauncherPath, minecraftSavePath, worldName).then(() => {
+        if (!minecraftSavePath || !worldName || worldId === null || !minecraftLauncherPath) return;
+        SaveUserData(minecraftLauncherPath, minecraftSavePath, worldName, worldId).then(() => {
             console.log("User settings saved successfully", minecraftSavePath, worldName);
+            setWorldName('');
+            setWorldId('');
+            ListWorlds().then(setWorlds);
         });
         // then check if local world is ahead of remote, if so we need to push (this happens if user syncs a new world)
         PushIfAhead().then(() => {
@@ -111,6 +190,11 @@ function Home() {
 
     return (
       <div className="flex flex-col justify-center items-center h-screen">
+        {needsPassphrase && (
+            <div className="absolute top-0 m-5 p-4 rounded-3xl bg-zinc-800 shadow-xl">
+                <PassphrasePrompt onUnlock={checkAuth}/>
+            </div>
+        )}
         <Link to="/about" className="absolute bottom-0 left-0 m-5 cursor-pointer opacity-75 hover:opacity-100 transition duration-300 group">
             <Info size={25} className="transition duration-300 group-hover:rotate-360"/>
         </Link>
@@ -118,7 +202,10 @@ function Home() {
             <div className="flex justify-start items-center flex-col gap-2 shadow-xl w-[500px] p-4 rounded-3xl bg-zinc-800">
                 <img src="/logo.png" alt="MineVCS Logo" className="w-20 h-20"/>
                 <h1 className="text-2xl font-bold text-zinc-50">MineVCS</h1>
-                <p className="text-lg">{showCode ? 'Please Enter Your Code' : 'Please Authorize Google Drive access'}</p>
+                <p className="text-lg">{showCode ? 'Finish signing in in your browser' : 'Please Authorize Google Drive access'}</p>
+                {showCode && (
+                <p className="text-xs opacity-75">Browser didn't come back? <span onClick={handlePasteAuth} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Get a code to paste</span></p>
+                )}
                 {showCode && (
                 <div className="flex justify-center items-center gap-2 mt-10">
                     <input 
@@ -142,7 +229,15 @@ function Home() {
         )
          : (
           <div className="flex justify-between items-start w-full h-screen">
-            <form className="flex gap-8 items-center justify-center flex-col w-1/2 h-screen" onSubmit={(e) => saveUserSettings(e)}>
+            <form className="flex gap-8 items-center justify-start flex-col w-1/2 h-screen overflow-y-auto py-10" onSubmit={(e) => saveUserSettings(e)}>
+                <Account onSwitch={() => setShowCode(true)}/>
+                <AuthBanner/>
+                {syncStatus?.state === 'error' && (
+                    <p className="text-red-500 text-xs w-80">{syncStatus.message}</p>
+                )}
+                {worlds.length > 0 && <PlayButton savesDir={minecraftSavePath}/>}
+                <StorageUsage storage={storage}/>
+                <QueuedPushes entries={queuedPushes}/>
                 <div className="flex justify-center items-start flex-col gap-8">
                     <div className="flex flex-col gap-2 items-start justify-center">
                         <div className="flex gap-2 items-center justify-center relative">
@@ -167,6 +262,7 @@ function Home() {
                             {showTooltip === 'save' && (<SaveTooltip/>)}
                         </div>
                         <div className="flex justify-center items-start gap-2 flex-col">
+                            <InstancePicker savesDir={minecraftSavePath} onSelect={setSavePath}/>
                             <input type="text" 
                                 placeholder={defaultMinecraftSavePath}
                                 id="file-path" 
@@ -179,20 +275,21 @@ function Home() {
                     </div>
                     <div className="flex flex-col gap-2 items-start justify-center">
                         <div className="flex gap-2 items-center justify-center relative">
-                            <label htmlFor="world-name">Minecraft World To Sync:</label>
-                        </div>
-                        <div className="flex justify-center items-center gap-2">
-                            <input type="text" placeholder="World Name" id="world-name" value={worldName} onChange={(e) => setWorldName(e.target.value)} className="w-80 border border-zinc-50 focus:ring-0 focus:outline-none rounded-md text-xs placeholder:opacity-50 px-2 py-3 bg-zinc-900 text-zinc-100"/>
+                            <label>Add A World To Sync:</label>
                         </div>
+                        <WorldPicker savesDir={minecraftSavePath} selected={worldName} selectedId={worldId} onSelect={(name, id) => { setWorldName(name); setWorldId(id); }} syncedCount={worlds.length}/>
                     </div>
                 </div>
                 <button type="submit" 
-                    className={getButtonClass(!worldName || !minecraftSavePath || !minecraftLauncherPath || !isAuthenticated) + ' group flex justify-center items-center gap-2 text-xs'} 
-                    disabled={!minecraftLauncherPath || !worldName || !minecraftSavePath || !isAuthenticated} 
+                    className={getButtonClass(!worldName || worldId === null || !minecraftSavePath || !minecraftLauncherPath || !isAuthenticated) + ' group flex justify-center items-center gap-2 text-xs'} 
+                    disabled={!minecraftLauncherPath || !worldName || worldId === null || !minecraftSavePath || !isAuthenticated} 
                 >
                     <span className="transition-transform duration-300 group-hover:rotate-45"><Settings/></span>
                     Save Settings
                 </button>
+                <Worlds worlds={worlds}/>
+                <TransferSettings/>
+                <ProfileSync/>
             </form>
             <Logs logs={logs}/>
           </div>
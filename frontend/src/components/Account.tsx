@@ -0,0 +1,52 @@
+import {useEffect, useState} from 'react';
+import {drive} from "../../wailsjs/go/models";
+import {GetAccount, SignOut, SwitchAccount} from "../../wailsjs/go/main/App";
+import {BrowserOpenURL, EventsOn} from "../../wailsjs/runtime";
+
+// the Google account being synced to, with ways to sign out or pick another one
+const Account = ({onSwitch} : {onSwitch: () => void}) => {
+    const [account, setAccount] = useState<drive.Account | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    const refresh = () => {
+        GetAccount().then(setAccount).catch((err) => {
+            console.error("Error getting the Google account", err);
+        });
+    }
+
+    useEffect(() => {
+        refresh();
+        const offAuthenticated = EventsOn("authenticated", refresh);
+        return () => offAuthenticated();
+    }, []);
+
+    const signOut = () => {
+        SignOut().catch((err) => setError(String(err)));
+    }
+
+    const switchAccount = () => {
+        SwitchAccount()
+            .then((url: string) => {
+                onSwitch();
+                BrowserOpenURL(url);
+            })
+            .catch((err) => setError(String(err)));
+    }
+
+    if (!account) return null;
+    return (
+        <div className="flex flex-col gap-1 w-80 text-xs">
+            <div className="flex items-center gap-2">
+                {account.photoUrl && <img src={account.photoUrl} alt="" referrerPolicy="no-referrer" className="w-6 h-6 rounded-full"/>}
+                <p>Syncing to <span className="font-bold">{account.name}</span> ({account.email})</p>
+            </div>
+            <div className="flex gap-3">
+                <p onClick={switchAccount} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Switch account</p>
+                <p onClick={signOut} className="cursor-pointer underline opacity-50 hover:opacity-100 transition duration-300">Sign out</p>
+            </div>
+            {error && <p className="text-red-500">{error}</p>}
+        </div>
+    )
+}
+
+export default Account;
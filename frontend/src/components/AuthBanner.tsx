@@ -0,0 +1,51 @@
+import {useEffect, useState} from 'react';
+import {main} from "../../wailsjs/go/models";
+import {GetAuthStatus, GoogleAuth, NarrowDriveAccess} from "../../wailsjs/go/main/App";
+import {BrowserOpenURL, EventsOn} from "../../wailsjs/runtime";
+
+// asks to sign in again once Google stops accepting the saved sign in, or to give up full Drive access
+const AuthBanner = () => {
+    const [status, setStatus] = useState<main.AuthStatus | null>(null);
+
+    useEffect(() => {
+        GetAuthStatus().then(setStatus).catch((error) => {
+            console.error("Error checking the Google sign in", error);
+        });
+        const offAuthStatus = EventsOn("authStatus", (data) => setStatus(data as main.AuthStatus));
+        return () => offAuthStatus();
+    }, []);
+
+    const signIn = () => {
+        GoogleAuth().then((url: string) => BrowserOpenURL(url));
+    }
+
+    const limitAccess = () => {
+        NarrowDriveAccess().then((url: string) => BrowserOpenURL(url)).catch((error) => {
+            console.error("Error limiting Drive access", error);
+        });
+    }
+
+    if (!status) return null;
+    if (status.state === 'fullAccess') {
+        return (
+            <div className="flex flex-col gap-1 w-80 text-xs border border-zinc-500 rounded-md p-2">
+                <p>{status.message}</p>
+                <p onClick={limitAccess} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Limit access</p>
+            </div>
+        )
+    }
+    if (status.state === 'expired' || status.state === 'revoked') {
+        return (
+            <div className="flex flex-col gap-1 w-80 text-xs border border-red-500 rounded-md p-2">
+                <p className="text-red-500">{status.message}</p>
+                <p onClick={signIn} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Sign in again</p>
+            </div>
+        )
+    }
+    if (status.state === 'offline') {
+        return <p className="text-xs opacity-50 w-80">{status.message}</p>
+    }
+    return null;
+}
+
+export default AuthBanner;
@@ -0,0 +1,39 @@
+import {useEffect, useState} from 'react';
+import {main} from "../../wailsjs/go/models";
+import {ListInstances} from "../../wailsjs/go/main/App";
+
+const launcherNames: Record<string, string> = {
+    vanilla: 'Minecraft',
+    prism: 'Prism',
+    multimc: 'MultiMC',
+    curseforge: 'CurseForge',
+    atlauncher: 'ATLauncher',
+};
+
+// lists the game folders of every launcher found on this machine, picking one fills in its saves folder
+const InstancePicker = ({savesDir, onSelect} : {savesDir: string, onSelect: (savesDir: string) => void}) => {
+    const [instances, setInstances] = useState<main.Instance[]>([]);
+
+    useEffect(() => {
+        ListInstances().then((data) => setInstances(data ?? [])).catch((error) => {
+            console.error("Error listing launcher instances", error);
+        });
+    }, []);
+
+    if (instances.length === 0) return null;
+    return (
+        <select
+            value={instances.some((i) => i.savesDir === savesDir) ? savesDir : ''}
+            onChange={(e) => e.target.value && onSelect(e.target.value)}
+            className="bg-zinc-900 text-zinc-100 text-xs rounded-md px-1 py-1 w-80 border border-zinc-50">
+            <option value="">Pick a launcher instance...</option>
+            {instances.map((instance) => (
+            <option key={instance.gameDir} value={instance.savesDir}>
+                {launcherNames[instance.launcher] ?? instance.launcher}: {instance.name}{instance.version && ` (${instance.version})`}
+            </option>
+            ))}
+        </select>
+    )
+}
+
+export default InstancePicker;
@@ -0,0 +1,33 @@
+import {useState} from 'react';
+import {UnlockTokenStorage} from "../../wailsjs/go/main/App";
+
+// machines without a keyring keep the Google sign in in a file encrypted with this passphrase
+const PassphrasePrompt = ({onUnlock} : {onUnlock: () => void}) => {
+    const [passphrase, setPassphrase] = useState<string>('');
+    const [error, setError] = useState<string | null>(null);
+
+    const unlock = (e: any) => {
+        e.preventDefault();
+        UnlockTokenStorage(passphrase)
+            .then(() => {
+                setPassphrase('');
+                setError(null);
+                onUnlock();
+            })
+            .catch((err) => setError(String(err)));
+    }
+
+    return (
+        <form onSubmit={unlock} className="flex flex-col gap-2 w-80 text-xs">
+            <p>No keyring was found on this machine, your Google sign in is kept in a file encrypted with a passphrase.</p>
+            <div className="flex gap-2 items-center">
+                <input type="password" placeholder="Passphrase" value={passphrase} onChange={(e) => setPassphrase(e.target.value)} autoFocus
+                    className="border border-zinc-50 focus:ring-0 focus:outline-none rounded-md text-xs placeholder:opacity-50 px-2 py-2 w-full bg-zinc-900 text-zinc-100"/>
+                <button type="submit" disabled={!passphrase} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Unlock</button>
+            </div>
+            {error && <p className="text-red-500">{error}</p>}
+        </form>
+    )
+}
+
+export default PassphrasePrompt;
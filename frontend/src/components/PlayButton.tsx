@@ -0,0 +1,54 @@
+import {useState} from 'react';
+import {Play as PlayIcon} from 'lucide-react';
+import {CheckIfAuthenticated, Play} from "../../wailsjs/go/main/App";
+
+// pulls the worlds of the picked game folder and only then starts its launcher. signed out nothing can be
+// pulled, so it asks before starting the game with the worlds already on this machine
+const PlayButton = ({savesDir} : {savesDir: string}) => {
+    const [starting, setStarting] = useState<boolean>(false);
+    const [error, setError] = useState<string | null>(null);
+    const [signedOut, setSignedOut] = useState<boolean>(false);
+
+    const start = (withoutPull: boolean) => {
+        setStarting(true);
+        setError(null);
+        setSignedOut(false);
+        Play(savesDir, withoutPull)
+            .catch((err) => setError(String(err)))
+            .finally(() => setStarting(false));
+    }
+
+    const play = () => {
+        CheckIfAuthenticated()
+            .then((authenticated) => authenticated ? start(false) : setSignedOut(true))
+            .catch(() => setSignedOut(true));
+    }
+
+    return (
+        <div className="flex flex-col items-center gap-1 w-80">
+            <button type="button" onClick={play} disabled={starting || signedOut}
+                className={`border rounded-md px-4 py-2 transition duration-300 flex justify-center items-center gap-2 text-xs ${starting || signedOut ? 'text-zinc-500 opacity-80 cursor-not-allowed' : 'text-zinc-50 cursor-pointer hover:bg-zinc-50 hover:text-zinc-900'}`}>
+                <PlayIcon size={15}/>
+                {starting ? 'Syncing before launch...' : 'Play'}
+            </button>
+            {signedOut && (
+                <div className="flex flex-col items-center gap-1 text-xs">
+                    <p className="text-yellow-400">Not signed in, worlds won't be pulled from Drive first.</p>
+                    <div className="flex gap-2">
+                        <button type="button" onClick={() => start(true)}
+                            className="border rounded-md px-2 py-1 text-zinc-50 hover:bg-zinc-50 hover:text-zinc-900 transition duration-300">
+                            Play without pulling
+                        </button>
+                        <button type="button" onClick={() => setSignedOut(false)}
+                            className="border rounded-md px-2 py-1 text-zinc-50 hover:bg-zinc-50 hover:text-zinc-900 transition duration-300">
+                            Cancel
+                        </button>
+                    </div>
+                </div>
+            )}
+            {error && <p className="text-red-500 text-xs">{error}</p>}
+        </div>
+    )
+}
+
+export default PlayButton;
@@ -0,0 +1,94 @@
+import {useEffect, useState} from 'react';
+import {main} from "../../wailsjs/go/models";
+import {GetProfileConflicts, GetProfileSettings, ResolveProfileConflict, SaveProfileSettings} from "../../wailsjs/go/main/App";
+import {EventsOn} from "../../wailsjs/runtime";
+
+const items = [
+    {id: 'options', label: 'Game settings (options.txt)'},
+    {id: 'servers', label: 'Server list (servers.dat)'},
+    {id: 'resourcepacks', label: 'Resource packs'},
+    {id: 'shaderpacks', label: 'Shader packs'},
+];
+
+const inputClass = "border border-zinc-50 focus:ring-0 focus:outline-none rounded-md text-xs placeholder:opacity-50 px-2 py-1 w-full bg-zinc-900 text-zinc-100";
+
+const ProfileSync = () => {
+    const [open, setOpen] = useState<boolean>(false);
+    const [settings, setSettings] = useState<main.ProfileSettings>(new main.ProfileSettings());
+    const [localOptions, setLocalOptions] = useState<string>('');
+    const [error, setError] = useState<string | null>(null);
+    const [conflicts, setConflicts] = useState<main.ProfileConflict[]>([]);
+
+    useEffect(() => {
+        GetProfileSettings().then((data) => {
+            setSettings(data);
+            setLocalOptions((data.localOptions ?? []).join(', '));
+        });
+        GetProfileConflicts().then((data) => setConflicts(data ?? []));
+        const offConflicts = EventsOn("profileConflicts", (data) => setConflicts((data ?? []) as main.ProfileConflict[]));
+        return () => offConflicts();
+    }, []);
+
+    const resolve = (item: string, keepLocal: boolean) => {
+        ResolveProfileConflict(item, keepLocal)
+            .then(() => setError(null))
+            .catch((err) => setError(String(err)));
+    }
+
+    // shown even while the section is closed, nothing syncs for these items until one copy is kept
+    const conflictList = conflicts.map((conflict) => (
+        <div key={conflict.item} className="flex flex-col gap-1 w-80 text-xs">
+            <p className="text-red-500">{conflict.path} changed both on this machine and on Drive</p>
+            <div className="flex gap-3">
+                <p onClick={() => resolve(conflict.item, true)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Keep this machine's</p>
+                <p onClick={() => resolve(conflict.item, false)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Keep Drive's</p>
+            </div>
+        </div>
+    ));
+
+    const toggle = (id: string) => {
+        const enabled = settings.items ?? [];
+        const next = enabled.includes(id) ? enabled.filter((item) => item !== id) : [...enabled, id];
+        setSettings(main.ProfileSettings.createFrom({...settings, items: next}));
+    }
+
+    const save = () => {
+        const keys = localOptions.split(',').map((key) => key.trim()).filter((key) => key.length > 0);
+        SaveProfileSettings(main.ProfileSettings.createFrom({...settings, localOptions: keys}))
+            .then(() => setError(null))
+            .catch((err) => setError(String(err)));
+    }
+
+    if (!open) {
+        return (
+            <>
+                <p onClick={() => setOpen(true)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">Settings & packs sync</p>
+                {conflictList}
+            </>
+        )
+    }
+
+    return (
+        <div className="flex flex-col gap-2 w-80 text-xs">
+            <p>Also sync these when Minecraft starts and exits:</p>
+            {items.map((item) => (
+            <label key={item.id} className="flex gap-2 items-center">
+                <input type="checkbox" checked={(settings.items ?? []).includes(item.id)} onChange={() => toggle(item.id)}/>
+                {item.label}
+            </label>
+            ))}
+            <label>Game folder (leave empty to use the one holding your first world)</label>
+            <input type="text" value={settings.gameDir} onChange={(e) => setSettings(main.ProfileSettings.createFrom({...settings, gameDir: e.target.value}))} className={inputClass}/>
+            <label>Settings kept on this machine only (options.txt keys)</label>
+            <textarea value={localOptions} onChange={(e) => setLocalOptions(e.target.value)} rows={3} className={inputClass}/>
+            {conflictList}
+            {error && <p className="text-red-500">{error}</p>}
+            <div className="flex gap-3">
+                <p onClick={save} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Save</p>
+                <p onClick={() => setOpen(false)} className="cursor-pointer underline opacity-50 hover:opacity-100 transition duration-300">Close</p>
+            </div>
+        </div>
+    )
+}
+
+export default ProfileSync;
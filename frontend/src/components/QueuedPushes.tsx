@@ -0,0 +1,34 @@
+import {main} from "../../wailsjs/go/models";
+import {PushQueuedSnapshot, DiscardQueuedSnapshot} from "../../wailsjs/go/main/App";
+
+const QueuedPushes = ({entries} : {entries: main.OutboxEntry[]}) => {
+    if (entries.length === 0) return null;
+    return (
+        <div className="flex flex-col gap-2 w-80">
+            <p className="text-xs">Waiting to be pushed:</p>
+            {entries.map((entry) => (
+            <div key={entry.id} className="flex flex-col gap-1 border border-zinc-500 rounded-md px-2 py-2">
+                <p className="text-xs">{entry.worldName} <span className="opacity-50">({new Date(entry.createdAt).toLocaleString()})</span></p>
+                {entry.conflict ? (
+                    <>
+                    <p className="text-xs text-yellow-400">Another machine pushed a newer version while this one was offline.</p>
+                    <div className="flex gap-3">
+                        <p onClick={() => PushQueuedSnapshot(entry.id)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">Push anyway</p>
+                        <p onClick={() => DiscardQueuedSnapshot(entry.id)} className="cursor-pointer underline text-red-400 hover:text-red-500 transition duration-300 text-xs">Discard local</p>
+                    </div>
+                    </>
+                ) : entry.deferred ? (
+                    <div className="flex gap-3">
+                        <p className="text-xs opacity-50">Waiting for the push window.</p>
+                        <p onClick={() => PushQueuedSnapshot(entry.id)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">Push now</p>
+                    </div>
+                ) : (
+                    entry.lastError && <p className="text-xs opacity-50">{entry.lastError}</p>
+                )}
+            </div>
+            ))}
+        </div>
+    )
+}
+
+export default QueuedPushes;
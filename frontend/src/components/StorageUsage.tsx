@@ -0,0 +1,31 @@
+import {main} from "../../wailsjs/go/models";
+
+export const formatBytes = (bytes: number) => {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    let i = 0;
+    while (bytes >= 1024 && i < units.length - 1) {
+        bytes /= 1024;
+        i++;
+    }
+    return `${bytes.toFixed(i === 0 ? 0 : 1)} ${units[i]}`;
+}
+
+const StorageUsage = ({storage} : {storage: main.StorageInfo | null}) => {
+    if (!storage) return null;
+    const percent = storage.limit > 0 ? Math.min(100, (storage.usage / storage.limit) * 100) : 0;
+    return (
+        <div className="flex flex-col gap-1 w-80">
+            <p className="text-xs">
+                Google Drive: {formatBytes(storage.usage)} used {storage.limit > 0 ? `of ${formatBytes(storage.limit)}` : '(unlimited)'}
+            </p>
+            {storage.limit > 0 && (
+                <div className="w-full h-1 rounded-md bg-zinc-700">
+                    <div className={`h-1 rounded-md ${percent > 90 ? 'bg-red-500' : 'bg-zinc-50'}`} style={{width: `${percent}%`}}/>
+                </div>
+            )}
+            <p className="text-xs opacity-50">MineVCS: {formatBytes(storage.minevcsUsage)}</p>
+        </div>
+    )
+}
+
+export default StorageUsage;
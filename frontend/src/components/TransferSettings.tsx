@@ -0,0 +1,70 @@
+import {useEffect, useState} from 'react';
+import {main} from "../../wailsjs/go/models";
+import {GetTransferSettings, SaveTransferSettings} from "../../wailsjs/go/main/App";
+
+const inputClass = "border border-zinc-50 focus:ring-0 focus:outline-none rounded-md text-xs placeholder:opacity-50 px-2 py-1 w-20 bg-zinc-900 text-zinc-100";
+
+const TransferSettings = () => {
+    const [open, setOpen] = useState<boolean>(false);
+    const [settings, setSettings] = useState<main.TransferSettings>(new main.TransferSettings());
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        GetTransferSettings().then(setSettings);
+    }, []);
+
+    const update = (field: keyof main.TransferSettings, value: string) => {
+        const numeric = field !== 'pushWindowStart' && field !== 'pushWindowEnd';
+        setSettings(main.TransferSettings.createFrom({...settings, [field]: numeric ? Number(value) || 0 : value}));
+    }
+
+    const save = () => {
+        SaveTransferSettings(settings)
+            .then(() => setError(null))
+            .catch((err) => setError(String(err)));
+    }
+
+    if (!open) {
+        return <p onClick={() => setOpen(true)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">Transfer settings</p>
+    }
+
+    return (
+        <div className="flex flex-col gap-2 w-80 text-xs">
+            <div className="flex justify-between items-center">
+                <label>Upload limit (KB/s, 0 = none)</label>
+                <input type="number" min={0} value={settings.uploadLimitKBps} onChange={(e) => update('uploadLimitKBps', e.target.value)} className={inputClass}/>
+            </div>
+            <div className="flex justify-between items-center">
+                <label>Download limit (KB/s, 0 = none)</label>
+                <input type="number" min={0} value={settings.downloadLimitKBps} onChange={(e) => update('downloadLimitKBps', e.target.value)} className={inputClass}/>
+            </div>
+            <div className="flex justify-between items-center">
+                <label>Postpone pushes over (MB, 0 = never)</label>
+                <input type="number" min={0} value={settings.deferPushesOverMB} onChange={(e) => update('deferPushesOverMB', e.target.value)} className={inputClass}/>
+            </div>
+            <div className="flex justify-between items-center">
+                <label>Push window</label>
+                <div className="flex gap-1 items-center">
+                    <input type="time" value={settings.pushWindowStart} onChange={(e) => update('pushWindowStart', e.target.value)} className={inputClass}/>
+                    <span>-</span>
+                    <input type="time" value={settings.pushWindowEnd} onChange={(e) => update('pushWindowEnd', e.target.value)} className={inputClass}/>
+                </div>
+            </div>
+            <div className="flex justify-between items-center">
+                <label>Push open worlds every (min, 0 = off)</label>
+                <input type="number" min={0} value={settings.periodicPushMinutes} onChange={(e) => update('periodicPushMinutes', e.target.value)} className={inputClass}/>
+            </div>
+            <div className="flex justify-between items-center">
+                <label>Wait for saves to settle (s, 0 = 30)</label>
+                <input type="number" min={0} value={settings.quietSeconds} onChange={(e) => update('quietSeconds', e.target.value)} className={inputClass}/>
+            </div>
+            {error && <p className="text-red-500">{error}</p>}
+            <div className="flex gap-3">
+                <p onClick={save} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300">Save</p>
+                <p onClick={() => setOpen(false)} className="cursor-pointer underline opacity-50 hover:opacity-100 transition duration-300">Close</p>
+            </div>
+        </div>
+    )
+}
+
+export default TransferSettings;
@@ -0,0 +1,97 @@
+import {useState, useEffect} from 'react';
+import {drive, main} from "../../wailsjs/go/models";
+import {DiscoverWorlds, ListCloudWorlds} from "../../wailsjs/go/main/App";
+import {formatBytes} from './StorageUsage';
+
+const formatDate = (time: string) => time ? new Date(time).toLocaleString() : 'Never played';
+
+// the id passed to AddWorld to sync a world as a new one even though Drive has one with the same name
+const newWorldId = 'new';
+
+// syncedCount only re-runs discovery when a world gets added or removed, so the synced flags stay current.
+// onSelect gets the world's folder name and which world on Drive it is: '' when no world there shares its name,
+// null while the user still has to choose
+const WorldPicker = ({savesDir, selected, selectedId, onSelect, syncedCount} : {savesDir: string, selected: string, selectedId: string | null, onSelect: (name: string, id: string | null) => void, syncedCount: number}) => {
+    const [worlds, setWorlds] = useState<main.LocalWorld[]>([]);
+    const [cloudWorlds, setCloudWorlds] = useState<drive.CloudWorld[]>([]);
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        ListCloudWorlds().then((data) => setCloudWorlds(data ?? [])).catch((error) => {
+            console.error("Error listing worlds on Drive", error);
+        });
+    }, []);
+
+    useEffect(() => {
+        setWorlds([]);
+        setError(null);
+        if (!savesDir) return;
+        DiscoverWorlds(savesDir).then((data) => setWorlds(data ?? [])).catch((error) => {
+            setError(String(error));
+        });
+    }, [savesDir, syncedCount]);
+
+    // worlds pushed from another machine that aren't in this saves folder yet, picking one pulls it here
+    const cloudOnly = cloudWorlds.filter((cw) => !worlds.some((w) => w.name === cw.name));
+    // worlds on Drive a local world might be, a name alone never decides that
+    const sameName = (name: string) => cloudWorlds.filter((cw) => cw.name === name);
+
+    if (!savesDir) {
+        return <p className="text-xs opacity-50 w-80">Set the save path above to see your worlds.</p>
+    }
+    if (error) {
+        return <p className="text-red-500 text-xs w-80">{error}</p>
+    }
+    if (worlds.length === 0 && cloudOnly.length === 0) {
+        return <p className="text-xs opacity-50 w-80">No worlds found in this saves folder.</p>
+    }
+    const rowClass = (name: string, disabled: boolean) =>
+        `flex gap-2 items-center border rounded-md px-2 py-2 transition duration-300 ${
+            disabled ? 'opacity-50 cursor-not-allowed border-zinc-700'
+            : name === selected ? 'border-zinc-50 cursor-pointer' : 'border-zinc-500 cursor-pointer hover:border-zinc-300'
+        }`
+    return (
+        <div className="flex flex-col gap-2 w-80 max-h-80 overflow-y-auto">
+            {worlds.map((world) => (
+            <div key={world.name} className="flex flex-col gap-1">
+            <div onClick={() => !world.synced && onSelect(world.name, sameName(world.name).length > 0 ? null : '')} className={rowClass(world.name, world.synced)}>
+                {world.icon
+                    ? <img src={world.icon} alt="" className="w-10 h-10 rounded-sm"/>
+                    : <div className="w-10 h-10 rounded-sm bg-zinc-700"/>}
+                <div className="flex flex-col min-w-0">
+                    <p className="text-xs truncate">{world.displayName}{world.synced && ' (synced)'}</p>
+                    <p className="text-xs opacity-50 truncate">{world.name} · {world.version || 'unknown version'} · {formatBytes(world.size)}</p>
+                    <p className="text-xs opacity-50 truncate">{world.error ? `Couldn't read level.dat: ${world.error}` : formatDate(world.lastPlayed)}</p>
+                </div>
+            </div>
+            {world.name === selected && !world.synced && sameName(world.name).length > 0 && (
+            <div className="flex flex-col gap-1 pl-2">
+                <p className="text-xs opacity-50">A world named {world.name} is already on Drive. Is this it?</p>
+                {sameName(world.name).map((cw) => (
+                <label key={cw.id} className="flex gap-2 items-center text-xs cursor-pointer">
+                    <input type="radio" checked={selectedId === cw.id} onChange={() => onSelect(world.name, cw.id)}/>
+                    Yes, the one pushed {formatDate(cw.modifiedTime)}
+                </label>
+                ))}
+                <label className="flex gap-2 items-center text-xs cursor-pointer">
+                    <input type="radio" checked={selectedId === newWorldId} onChange={() => onSelect(world.name, newWorldId)}/>
+                    No, sync it as a new world
+                </label>
+            </div>
+            )}
+            </div>
+            ))}
+            {cloudOnly.map((world) => (
+            <div key={world.id} onClick={() => onSelect(world.name, world.id)} className={rowClass(world.name, false)}>
+                <div className="w-10 h-10 rounded-sm bg-zinc-700"/>
+                <div className="flex flex-col min-w-0">
+                    <p className="text-xs truncate">{world.name}</p>
+                    <p className="text-xs opacity-50 truncate">On Drive only, pushed {formatDate(world.modifiedTime)}</p>
+                </div>
+            </div>
+            ))}
+        </div>
+    )
+}
+
+export default WorldPicker;
@@ -0,0 +1,68 @@
+import {useState, useEffect} from 'react';
+import {drive, main} from "../../wailsjs/go/models";
+import {LinkWorld, ListCloudWorlds, RemoveWorld, SetWorldPolicy} from "../../wailsjs/go/main/App";
+
+const stateColour = (state: string) => {
+    if (state === 'error') return 'text-red-500';
+    if (state === 'ok') return 'text-green-400';
+    return 'opacity-50';
+}
+
+// a world synced before it had a Drive id, whose name other worlds on Drive share. the user says which one it is
+const LinkChoice = ({world} : {world: main.WorldStatus}) => {
+    const [sameName, setSameName] = useState<drive.CloudWorld[]>([]);
+
+    useEffect(() => {
+        ListCloudWorlds().then((data) => setSameName((data ?? []).filter((cw) => cw.name === world.name))).catch((error) => {
+            console.error("Error listing worlds on Drive", error);
+        });
+    }, [world.name]);
+
+    if (sameName.length === 0) return null;
+    const link = (id: string) => LinkWorld(world.savesDir, world.name, id).catch((error) => console.error("Error linking world", error));
+    return (
+        <div className="flex flex-col gap-1">
+            {sameName.map((cw) => (
+            <p key={cw.id} onClick={() => link(cw.id)} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">
+                It's the one pushed {new Date(cw.modifiedTime).toLocaleString()}
+            </p>
+            ))}
+            <p onClick={() => link('new')} className="cursor-pointer underline text-blue-400 hover:text-blue-500 transition duration-300 text-xs">It's a new world</p>
+        </div>
+    )
+}
+
+const Worlds = ({worlds} : {worlds: main.WorldStatus[]}) => {
+    if (worlds.length === 0) {
+        return <p className="text-xs opacity-50 w-80">No worlds synced yet, add one above.</p>
+    }
+    return (
+        <div className="flex flex-col gap-2 w-80">
+            <p className="text-xs">Synced worlds:</p>
+            {worlds.map((world) => (
+            <div key={world.savesDir + '/' + world.name} className="flex flex-col gap-1 border border-zinc-500 rounded-md px-2 py-2">
+                <div className="flex justify-between items-center">
+                    <p className="text-xs">{world.name}</p>
+                    <select
+                        value={world.policy}
+                        onChange={(e) => SetWorldPolicy(world.savesDir, world.name, e.target.value)}
+                        className="bg-zinc-900 text-zinc-100 text-xs rounded-md px-1">
+                        <option value="sync">Push & pull</option>
+                        <option value="push">Push only</option>
+                        <option value="pull">Pull only</option>
+                        <option value="paused">Paused</option>
+                    </select>
+                </div>
+                <p className="text-xs opacity-50 truncate">{world.savesDir}</p>
+                <p className={`text-xs ${stateColour(world.status.state)}`}>
+                    {world.pending ? 'Push waiting to be uploaded' : (world.status.message || 'Waiting for Minecraft')}
+                </p>
+                {!world.id && world.status.state === 'error' && <LinkChoice world={world}/>}
+                <p onClick={() => RemoveWorld(world.savesDir, world.name)} className="cursor-pointer underline text-red-400 hover:text-red-500 transition duration-300 text-xs">Stop syncing</p>
+            </div>
+            ))}
+        </div>
+    )
+}
+
+export default Worlds;
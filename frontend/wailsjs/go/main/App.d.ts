@@ -1,19 +1,76 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {drive} from '../models';
 import {main} from '../models';
 
+export function AddWorld(arg1:string,arg2:string,arg3:string):Promise<void>;
+
 export function CheckIfAuthenticated():Promise<boolean>;
 
 export function CheckMinecraftRunning():Promise<boolean>;
 
+export function DiscardQueuedSnapshot(arg1:string):Promise<void>;
+
+export function DiscoverWorlds(arg1:string):Promise<Array<main.LocalWorld>>;
+
+export function GetAccount():Promise<drive.Account>;
+
+export function GetAuthStatus():Promise<main.AuthStatus>;
+
 export function GetDefaultPaths():Promise<main.DefaultPaths>;
 
+export function GetProfileConflicts():Promise<Array<main.ProfileConflict>>;
+
+export function GetProfileSettings():Promise<main.ProfileSettings>;
+
+export function GetQueuedPushes():Promise<Array<main.OutboxEntry>>;
+
+export function GetStorageInfo():Promise<main.StorageInfo>;
+
+export function GetSyncStatus():Promise<main.SyncStatus>;
+
+export function GetTokenStorage():Promise<main.TokenStorage>;
+
+export function GetTransferSettings():Promise<main.TransferSettings>;
+
 export function GetUserData():Promise<main.UserData>;
 
 export function GoogleAuth():Promise<string>;
 
+export function GoogleAuthPaste():Promise<string>;
+
+export function LinkWorld(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function ListCloudWorlds():Promise<Array<drive.CloudWorld>>;
+
+export function ListInstances():Promise<Array<main.Instance>>;
+
+export function ListWorlds():Promise<Array<main.WorldStatus>>;
+
+export function NarrowDriveAccess():Promise<string>;
+
+export function Play(arg1:string,arg2:boolean):Promise<void>;
+
 export function PushIfAhead():Promise<void>;
 
-export function SaveUserData(arg1:string,arg2:string,arg3:string):Promise<void>;
+export function PushQueuedSnapshot(arg1:string):Promise<void>;
+
+export function RemoveWorld(arg1:string,arg2:string):Promise<void>;
+
+export function ResolveProfileConflict(arg1:string,arg2:boolean):Promise<void>;
+
+export function SaveProfileSettings(arg1:main.ProfileSettings):Promise<void>;
+
+export function SaveTransferSettings(arg1:main.TransferSettings):Promise<void>;
+
+export function SaveUserData(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function SetWorldPolicy(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SignOut():Promise<void>;
+
+export function SwitchAccount():Promise<string>;
+
+export function UnlockTokenStorage(arg1:string):Promise<void>;
 
 export function UserAuthCode(arg1:string):Promise<void>;
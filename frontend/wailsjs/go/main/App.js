@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddWorld(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddWorld'](arg1, arg2, arg3);
+}
+
 export function CheckIfAuthenticated() {
   return window['go']['main']['App']['CheckIfAuthenticated']();
 }
@@ -10,10 +14,54 @@ export function CheckMinecraftRunning() {
   return window['go']['main']['App']['CheckMinecraftRunning']();
 }
 
+export function DiscardQueuedSnapshot(arg1) {
+  return window['go']['main']['App']['DiscardQueuedSnapshot'](arg1);
+}
+
+export function DiscoverWorlds(arg1) {
+  return window['go']['main']['App']['DiscoverWorlds'](arg1);
+}
+
+export function GetAccount() {
+  return window['go']['main']['App']['GetAccount']();
+}
+
+export function GetAuthStatus() {
+  return window['go']['main']['App']['GetAuthStatus']();
+}
+
 export function GetDefaultPaths() {
   return window['go']['main']['App']['GetDefaultPaths']();
 }
 
+export function GetProfileConflicts() {
+  return window['go']['main']['App']['GetProfileConflicts']();
+}
+
+export function GetProfileSettings() {
+  return window['go']['main']['App']['GetProfileSettings']();
+}
+
+export function GetQueuedPushes() {
+  return window['go']['main']['App']['GetQueuedPushes']();
+}
+
+export function GetStorageInfo() {
+  return window['go']['main']['App']['GetStorageInfo']();
+}
+
+export function GetSyncStatus() {
+  return window['go']['main']['App']['GetSyncStatus']();
+}
+
+export function GetTokenStorage() {
+  return window['go']['main']['App']['GetTokenStorage']();
+}
+
+export function GetTransferSettings() {
+  return window['go']['main']['App']['GetTransferSettings']();
+}
+
 export function GetUserData() {
   return window['go']['main']['App']['GetUserData']();
 }
@@ -22,12 +70,76 @@ export function GoogleAuth() {
   return window['go']['main']['App']['GoogleAuth']();
 }
 
+export function GoogleAuthPaste() {
+  return window['go']['main']['App']['GoogleAuthPaste']();
+}
+
+export function LinkWorld(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LinkWorld'](arg1, arg2, arg3);
+}
+
+export function ListCloudWorlds() {
+  return window['go']['main']['App']['ListCloudWorlds']();
+}
+
+export function ListInstances() {
+  return window['go']['main']['App']['ListInstances']();
+}
+
+export function ListWorlds() {
+  return window['go']['main']['App']['ListWorlds']();
+}
+
+export function NarrowDriveAccess() {
+  return window['go']['main']['App']['NarrowDriveAccess']();
+}
+
+export function Play(arg1, arg2) {
+  return window['go']['main']['App']['Play'](arg1, arg2);
+}
+
 export function PushIfAhead() {
   return window['go']['main']['App']['PushIfAhead']();
 }
 
-export function SaveUserData(arg1, arg2, arg3) {
-  return window['go']['main']['App']['SaveUserData'](arg1, arg2, arg3);
+export function PushQueuedSnapshot(arg1) {
+  return window['go']['main']['App']['PushQueuedSnapshot'](arg1);
+}
+
+export function RemoveWorld(arg1, arg2) {
+  return window['go']['main']['App']['RemoveWorld'](arg1, arg2);
+}
+
+export function ResolveProfileConflict(arg1, arg2) {
+  return window['go']['main']['App']['ResolveProfileConflict'](arg1, arg2);
+}
+
+export function SaveProfileSettings(arg1) {
+  return window['go']['main']['App']['SaveProfileSettings'](arg1);
+}
+
+export function SaveTransferSettings(arg1) {
+  return window['go']['main']['App']['SaveTransferSettings'](arg1);
+}
+
+export function SaveUserData(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveUserData'](arg1, arg2, arg3, arg4);
+}
+
+export function SetWorldPolicy(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetWorldPolicy'](arg1, arg2, arg3);
+}
+
+export function SignOut() {
+  return window['go']['main']['App']['SignOut']();
+}
+
+export function SwitchAccount() {
+  return window['go']['main']['App']['SwitchAccount']();
+}
+
+export function UnlockTokenStorage(arg1) {
+  return window['go']['main']['App']['UnlockTokenStorage'](arg1);
 }
 
 export function UserAuthCode(arg1) {
@@ -1,5 +1,57 @@
+export namespace drive {
+	
+	export class Account {
+	    name: string;
+	    email: string;
+	    photoUrl: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Account(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.email = source["email"];
+	        this.photoUrl = source["photoUrl"];
+	    }
+	}
+	export class CloudWorld {
+	    id: string;
+	    name: string;
+	    folderId: string;
+	    modifiedTime: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CloudWorld(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.folderId = source["folderId"];
+	        this.modifiedTime = source["modifiedTime"];
+	    }
+	}
+}
+
 export namespace main {
 	
+	export class AuthStatus {
+	    state: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.message = source["message"];
+	    }
+	}
 	export class DefaultPaths {
 	    minecraftLauncherPath: string;
 	    minecraftSavePath: string;
@@ -14,10 +66,193 @@ export namespace main {
 	        this.minecraftSavePath = source["minecraftSavePath"];
 	    }
 	}
+	export class Instance {
+	    launcher: string;
+	    id: string;
+	    name: string;
+	    gameDir: string;
+	    savesDir: string;
+	    version: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Instance(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.launcher = source["launcher"];
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.gameDir = source["gameDir"];
+	        this.savesDir = source["savesDir"];
+	        this.version = source["version"];
+	    }
+	}
+	export class LocalWorld {
+	    name: string;
+	    displayName: string;
+	    icon: string;
+	    size: number;
+	    lastPlayed: string;
+	    version: string;
+	    dataVersion: number;
+	    synced: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LocalWorld(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.displayName = source["displayName"];
+	        this.icon = source["icon"];
+	        this.size = source["size"];
+	        this.lastPlayed = source["lastPlayed"];
+	        this.version = source["version"];
+	        this.dataVersion = source["dataVersion"];
+	        this.synced = source["synced"];
+	        this.error = source["error"];
+	    }
+	}
+	export class OutboxEntry {
+	    id: string;
+	    worldId: string;
+	    worldName: string;
+	    savesDir: string;
+	    createdAt: string;
+	    queuedSince: string;
+	    attempts: number;
+	    lastError: string;
+	    conflict: boolean;
+	    deferred: boolean;
+	    dataVersion: number;
+	    versionName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutboxEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.worldId = source["worldId"];
+	        this.worldName = source["worldName"];
+	        this.savesDir = source["savesDir"];
+	        this.createdAt = source["createdAt"];
+	        this.queuedSince = source["queuedSince"];
+	        this.attempts = source["attempts"];
+	        this.lastError = source["lastError"];
+	        this.conflict = source["conflict"];
+	        this.deferred = source["deferred"];
+	        this.dataVersion = source["dataVersion"];
+	        this.versionName = source["versionName"];
+	    }
+	}
+	export class ProfileConflict {
+	    item: string;
+	    path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileConflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.item = source["item"];
+	        this.path = source["path"];
+	    }
+	}
+	export class ProfileSettings {
+	    items: string[];
+	    gameDir: string;
+	    localOptions: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.items = source["items"];
+	        this.gameDir = source["gameDir"];
+	        this.localOptions = source["localOptions"];
+	    }
+	}
+	export class StorageInfo {
+	    limit: number;
+	    usage: number;
+	    minevcsUsage: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new StorageInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.limit = source["limit"];
+	        this.usage = source["usage"];
+	        this.minevcsUsage = source["minevcsUsage"];
+	    }
+	}
+	export class SyncStatus {
+	    state: string;
+	    message: string;
+	    time: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SyncStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.message = source["message"];
+	        this.time = source["time"];
+	    }
+	}
+	export class TokenStorage {
+	    storage: string;
+	    needsPassphrase: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TokenStorage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.storage = source["storage"];
+	        this.needsPassphrase = source["needsPassphrase"];
+	    }
+	}
+	export class TransferSettings {
+	    uploadLimitKBps: number;
+	    downloadLimitKBps: number;
+	    deferPushesOverMB: number;
+	    pushWindowStart: string;
+	    pushWindowEnd: string;
+	    periodicPushMinutes: number;
+	    quietSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransferSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uploadLimitKBps = source["uploadLimitKBps"];
+	        this.downloadLimitKBps = source["downloadLimitKBps"];
+	        this.deferPushesOverMB = source["deferPushesOverMB"];
+	        this.pushWindowStart = source["pushWindowStart"];
+	        this.pushWindowEnd = source["pushWindowEnd"];
+	        this.periodicPushMinutes = source["periodicPushMinutes"];
+	        this.quietSeconds = source["quietSeconds"];
+	    }
+	}
 	export class UserData {
 	    minecraftLauncher: string;
-	    minecraftDirectory: string;
-	    worldName: string;
+	    worlds: WorldStatus[];
 	
 	    static createFrom(source: any = {}) {
 	        return new UserData(source);
@@ -26,9 +261,66 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.minecraftLauncher = source["minecraftLauncher"];
-	        this.minecraftDirectory = source["minecraftDirectory"];
-	        this.worldName = source["worldName"];
+	        this.worlds = this.convertValues(source["worlds"], WorldStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class WorldStatus {
+	    id: string;
+	    name: string;
+	    savesDir: string;
+	    policy: string;
+	    status: SyncStatus;
+	    pending: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new WorldStatus(source);
 	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.savesDir = source["savesDir"];
+	        this.policy = source["policy"];
+	        this.status = this.convertValues(source["status"], SyncStatus);
+	        this.pending = source["pending"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
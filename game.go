@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// main classes the game JVM is started with. vanilla, the mod loaders' entry points and the wrappers
+// third-party launchers put in front of them
+var gameMainClasses = []string{
+	"net.minecraft.client.main.Main",
+	"net.fabricmc.loader.impl.launch.knot.KnotClient",
+	"net.fabricmc.loader.launch.knot.KnotClient",
+	"org.quiltmc.loader.impl.launch.knot.KnotClient",
+	"cpw.mods.modlauncher.Launcher",
+	"cpw.mods.bootstraplauncher.BootstrapLauncher",
+	"net.minecraft.launchwrapper.Launch",
+	"io.github.zekerzhayard.forgewrapper.installer.Main",
+	"org.prismlauncher.EntryPoint",
+	"org.multimc.EntryPoint",
+}
+
+// GameProcess is a running Minecraft client
+type GameProcess struct {
+	PID     int32  `json:"pid"`
+	GameDir string `json:"gameDir"` // empty if it couldn't be worked out
+	Version string `json:"version"` // the --version argument, usually the launcher's version id
+}
+
+func isGameCommandLine(args []string) bool {
+	for _, arg := range args {
+		for _, class := range gameMainClasses {
+			if arg == class {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// value of a "--flag value" argument
+func argValue(args []string, flag string) string {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// finds the running game JVMs. the game directory comes from --gameDir, or from the working directory for
+// launchers like Prism and MultiMC that hand the arguments over on stdin and start the game inside the instance
+func findGameProcesses() ([]GameProcess, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	var games []GameProcess
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil || !strings.Contains(strings.ToLower(name), "java") {
+			continue
+		}
+		args, err := p.CmdlineSlice()
+		if err != nil || !isGameCommandLine(args) {
+			continue
+		}
+		game := GameProcess{PID: p.Pid, GameDir: argValue(args, "--gameDir"), Version: argValue(args, "--version")}
+		if game.GameDir == "" {
+			game.GameDir, _ = p.Cwd()
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// GameVersion is the Minecraft version a game directory runs
+type GameVersion struct {
+	ID          string `json:"id"`          // the launcher's version id, e.g. "fabric-loader-0.15.7-1.20.4"
+	Name        string `json:"name"`        // e.g. "1.20.4"
+	DataVersion int32  `json:"dataVersion"` // the newest world format it can open, 0 if unknown
+}
+
+// lines in latest.log that name the version the game was started with. vanilla doesn't log it but the
+// mod loaders do, and forge prints its launch arguments
+var logVersionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Loading Minecraft (\S+) with`),
+	regexp.MustCompile(`--version, ([^,\]]+)`),
+	regexp.MustCompile(`Minecraft Version: (\S+)`),
+}
+
+type launcherProfiles struct {
+	Profiles map[string]struct {
+		LastUsed      string `json:"lastUsed"`
+		LastVersionID string `json:"lastVersionId"`
+	} `json:"profiles"`
+}
+
+// the parts of versions/<id>/<id>.json and of version.json inside the jar we need
+type versionManifest struct {
+	ID           string `json:"id"`
+	InheritsFrom string `json:"inheritsFrom"` // modded versions point at the vanilla one they're built on
+	Type         string `json:"type"`         // "release" or "snapshot"
+}
+
+type jarVersion struct {
+	Name         string `json:"name"`
+	WorldVersion int32  `json:"world_version"`
+}
+
+// works out which Minecraft version a game directory (the folder holding saves/) last ran, first from
+// latest.log and then from the launcher's most recently used profile
+func detectGameVersion(gameDir string) (GameVersion, error) {
+	id := versionFromLog(filepath.Join(gameDir, "logs", "latest.log"))
+	if id == "" {
+		id = versionFromProfiles(gameDir)
+	}
+	if id == "" {
+		return GameVersion{}, fmt.Errorf("unable to tell which Minecraft version %s runs", gameDir)
+	}
+	return readGameVersion(gameDir, id)
+}
+
+func versionFromLog(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	// the version is logged right at startup
+	for lines := 0; scanner.Scan() && lines < 500; lines++ {
+		for _, pattern := range logVersionPatterns {
+			if m := pattern.FindStringSubmatch(scanner.Text()); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+func versionFromProfiles(gameDir string) string {
+	data, err := os.ReadFile(filepath.Join(gameDir, "launcher_profiles.json"))
+	if err != nil {
+		return ""
+	}
+	var profiles launcherProfiles
+	if json.Unmarshal(data, &profiles) != nil {
+		return ""
+	}
+	var id string
+	var newest time.Time
+	for _, p := range profiles.Profiles {
+		used, _ := time.Parse(time.RFC3339, p.LastUsed)
+		if id == "" || used.After(newest) {
+			id, newest = p.LastVersionID, used
+		}
+	}
+	switch id {
+	case "latest-release":
+		return newestInstalled(gameDir, false)
+	case "latest-snapshot":
+		return newestInstalled(gameDir, true)
+	}
+	return id
+}
+
+// the installed vanilla version with the highest world version, for profiles that just follow the latest one
+func newestInstalled(gameDir string, snapshots bool) string {
+	entries, err := os.ReadDir(filepath.Join(gameDir, "versions"))
+	if err != nil {
+		return ""
+	}
+	var best string
+	var bestVersion int32
+	for _, entry := range entries {
+		manifest, err := readVersionManifest(gameDir, entry.Name())
+		if err != nil || manifest.InheritsFrom != "" || (manifest.Type != "release" && !snapshots) {
+			continue
+		}
+		if v, err := readGameVersion(gameDir, entry.Name()); err == nil && v.DataVersion > bestVersion {
+			best, bestVersion = entry.Name(), v.DataVersion
+		}
+	}
+	return best
+}
+
+func readVersionManifest(gameDir string, id string) (versionManifest, error) {
+	var manifest versionManifest
+	data, err := os.ReadFile(filepath.Join(gameDir, "versions", id, id+".json"))
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, json.Unmarshal(data, &manifest)
+}
+
+// reads the world version out of version.json inside the version's jar (there since 1.14). modded versions
+// usually have no jar of their own so the vanilla version they inherit from is used
+func readGameVersion(gameDir string, id string) (GameVersion, error) {
+	version := GameVersion{ID: id, Name: id}
+	jarID := id
+	for i := 0; i < 5; i++ {
+		manifest, err := readVersionManifest(gameDir, jarID)
+		if err != nil || manifest.InheritsFrom == "" {
+			break
+		}
+		jarID = manifest.InheritsFrom
+		version.Name = jarID
+	}
+	r, err := zip.OpenReader(filepath.Join(gameDir, "versions", jarID, jarID+".jar"))
+	if err != nil {
+		// not installed through this launcher, the name is still worth showing
+		return version, nil
+	}
+	defer r.Close()
+	f, err := r.Open("version.json")
+	if err != nil {
+		return version, nil
+	}
+	defer f.Close()
+	var info jarVersion
+	if err := json.NewDecoder(f).Decode(&info); err != nil {
+		return version, fmt.Errorf("unable to read version.json of %s: %w", jarID, err)
+	}
+	if info.Name != "" {
+		version.Name = info.Name
+	}
+	version.DataVersion = info.WorldVersion
+	return version, nil
+}
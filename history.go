@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"drive/drive"
+)
+
+// where a copy of a world that can be restored comes from. Drive only keeps the latest push, older copies
+// are the local backups taken before a pull upgraded or a restore replaced the world
+const (
+	SourceDrive  = "drive"  // the latest push
+	SourceQueued = "queued" // a snapshot in the outbox that hasn't been uploaded yet
+	SourceBackup = "backup" // ~/.minevcs/backups
+)
+
+// WorldVersion is one copy of a world that can be restored
+type WorldVersion struct {
+	ID          string `json:"id"` // what to pass to restoreWorld
+	Source      string `json:"source"`
+	Time        string `json:"time"`
+	VersionName string `json:"versionName"` // the Minecraft version that saved it, if known
+	Size        int64  `json:"size"`
+}
+
+// lists every copy of the world that can be restored, newest first. Drive is skipped when it can't be reached
+func (a *App) worldHistory(w WorldConfig) ([]WorldVersion, error) {
+	var versions []WorldVersion
+	if latest, err := a.latestPush(w); err != nil {
+		println("Not listing the copy on Drive:", err.Error())
+	} else if latest != nil {
+		versions = append(versions, *latest)
+	}
+
+	for _, entry := range a.outbox.list() {
+		if entry.world().key() == w.key() {
+			versions = append(versions, WorldVersion{
+				ID:          entry.ID,
+				Source:      SourceQueued,
+				Time:        entry.CreatedAt,
+				VersionName: entry.VersionName,
+				Size:        a.outbox.snapshotSize(entry),
+			})
+		}
+	}
+
+	if w.ID == "" {
+		w.ID = a.worldID(w)
+	}
+	if w.ID != "" {
+		entries, _ := os.ReadDir(filepath.Join(backupsDir(), w.ID))
+		for _, entry := range entries {
+			version, ok := backupVersion(w, entry.Name())
+			if ok {
+				versions = append(versions, version)
+			}
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Time > versions[j].Time
+	})
+	return versions, nil
+}
+
+// the copy on Drive, nil if nothing was pushed yet
+func (a *App) latestPush(w WorldConfig) (*WorldVersion, error) {
+	if !drive.Reachable() {
+		return nil, fmt.Errorf("Drive can't be reached")
+	}
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		return nil, err
+	}
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return nil, err
+	}
+	zipFile, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld)
+	if errors.Is(err, drive.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &WorldVersion{
+		ID:          SourceDrive,
+		Source:      SourceDrive,
+		Time:        zipFile.ModifiedTime,
+		VersionName: zipFile.AppProperties["versionName"],
+		Size:        zipFile.Size,
+	}, nil
+}
+
+// the id the world has in the config, which resolveWorld may have just filled in
+func (a *App) worldID(w WorldConfig) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if i, ok := a.findWorld(w.SavesDir, w.Name); ok {
+		return a.worlds[i].ID
+	}
+	return ""
+}
+
+// reads a backup folder, named <time>-<data version>-<version name> by backupWorld
+func backupVersion(w WorldConfig, name string) (WorldVersion, bool) {
+	zipPath, err := backupZip(w, name)
+	if err != nil {
+		return WorldVersion{}, false
+	}
+	parts := strings.SplitN(name, "-", 3)
+	taken, err := time.Parse("20060102T150405Z", parts[0])
+	if err != nil {
+		return WorldVersion{}, false
+	}
+	version := WorldVersion{ID: name, Source: SourceBackup, Time: taken.Format(time.RFC3339)}
+	if len(parts) == 3 {
+		if dataVersion, _ := strconv.Atoi(parts[1]); dataVersion != 0 {
+			version.VersionName = parts[2]
+		}
+	}
+	if info, err := os.Stat(zipPath); err == nil {
+		version.Size = info.Size()
+	}
+	return version, true
+}
+
+func backupZip(w WorldConfig, name string) (string, error) {
+	if name != filepath.Base(name) || w.ID == "" {
+		return "", fmt.Errorf("no backup %s of %s", name, w.Name)
+	}
+	matches, _ := filepath.Glob(filepath.Join(backupsDir(), w.ID, name, "*.zip"))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no backup %s of %s", name, w.Name)
+	}
+	return matches[0], nil
+}
+
+// replaces the local world with one of the copies from worldHistory. the local world is backed up first so
+// the restore can be undone the same way
+func (a *App) restoreWorld(w WorldConfig, id string) error {
+	if err := w.waitUntilClosed("restore", 0); err != nil {
+		return err
+	}
+	if id == SourceDrive {
+		w, err := a.resolveWorld(w)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(w.path()); err == nil {
+			backup, err := a.backupWorld(w)
+			if err != nil {
+				return fmt.Errorf("unable to back up %s first: %w", w.Name, err)
+			}
+			a.printAndEmit("Local world backed up to " + backup + " 💾")
+		}
+		return a.pullWorld(w)
+	}
+
+	var zipPath string
+	if entry, ok := a.outbox.get(id); ok && entry.world().key() == w.key() {
+		zipPath = a.outbox.zipPath(entry)
+	} else {
+		if w.ID == "" {
+			if resolved, err := a.resolveWorld(w); err == nil {
+				w = resolved
+			}
+		}
+		path, err := backupZip(w, id)
+		if err != nil {
+			return err
+		}
+		zipPath = path
+	}
+	// extracted from a copy: backing up the current world may prune the very backup being restored
+	home, _ := os.UserHomeDir()
+	tmpDir, err := os.MkdirTemp(filepath.Join(home, ".minevcs"), "restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpZip := filepath.Join(tmpDir, w.Name+".zip")
+	if err := copyFile(zipPath, tmpZip); err != nil {
+		return err
+	}
+	extractDir, err := a.unzipFolder(tmpZip, false)
+	if err != nil {
+		return fmt.Errorf("the copy failed verification, local world left untouched: %w", err)
+	}
+	if err := w.waitUntilClosed("restore", 0); err != nil {
+		return err
+	}
+	backup, err := a.replaceWorld(w, extractDir, w.ID != "")
+	if err != nil {
+		return err
+	}
+	if backup != "" {
+		a.printAndEmit("Local world backed up to " + backup + " 💾")
+	}
+	a.printAndEmit(w.Name + " restored, push it to make it the copy on Drive ✅")
+	a.rewatch()
+	a.setWorldStatus(w, "ok", "Restored")
+	return nil
+}
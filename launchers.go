@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// launchers MineVCS knows how to find instances for
+const (
+	LauncherVanilla    = "vanilla"
+	LauncherPrism      = "prism"
+	LauncherMultiMC    = "multimc"
+	LauncherCurseForge = "curseforge"
+	LauncherATLauncher = "atlauncher"
+)
+
+// Instance is one game directory (a .minecraft folder) belonging to a launcher
+type Instance struct {
+	Launcher string `json:"launcher"`
+	ID       string `json:"id"` // the launcher's own name for the instance (its folder), used to start it directly
+	Name     string `json:"name"`
+	GameDir  string `json:"gameDir"`  // absolute
+	SavesDir string `json:"savesDir"` // stored the same way as a world's saves folder
+	Version  string `json:"version"`  // Minecraft version, empty if the launcher doesn't say
+}
+
+// where each launcher keeps its data, for every way it can be installed on this OS. Flatpak and Snap
+// sandbox the launcher's home so its files end up under ~/.var/app or ~/snap
+func launcherRoots(home string) map[string][]string {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		appData = filepath.Join(home, "AppData", "Roaming")
+	}
+	appSupport := filepath.Join(home, "Library", "Application Support")
+	switch runtime.GOOS {
+	case "windows":
+		return map[string][]string{
+			LauncherVanilla:    {filepath.Join(appData, ".minecraft")},
+			LauncherPrism:      {filepath.Join(appData, "PrismLauncher")},
+			LauncherMultiMC:    {filepath.Join(appData, "MultiMC"), filepath.Join(home, "MultiMC")},
+			LauncherCurseForge: {filepath.Join(home, "curseforge", "minecraft")},
+			LauncherATLauncher: {filepath.Join(appData, "ATLauncher")},
+		}
+	case "darwin":
+		return map[string][]string{
+			LauncherVanilla:    {filepath.Join(appSupport, "minecraft")},
+			LauncherPrism:      {filepath.Join(appSupport, "PrismLauncher")},
+			LauncherMultiMC:    {filepath.Join(appSupport, "MultiMC")},
+			LauncherCurseForge: {filepath.Join(home, "Documents", "curseforge", "minecraft")},
+			LauncherATLauncher: {filepath.Join(appSupport, "ATLauncher")},
+		}
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return map[string][]string{
+		LauncherVanilla: {
+			filepath.Join(home, ".minecraft"),
+			filepath.Join(home, ".var", "app", "com.mojang.Minecraft", ".minecraft"),
+			filepath.Join(home, "snap", "mc-installer", "current", ".minecraft"),
+		},
+		LauncherPrism: {
+			filepath.Join(dataHome, "PrismLauncher"),
+			filepath.Join(home, ".var", "app", "org.prismlauncher.PrismLauncher", "data", "PrismLauncher"),
+			filepath.Join(home, "snap", "prismlauncher", "current", ".local", "share", "PrismLauncher"),
+		},
+		LauncherMultiMC: {
+			filepath.Join(dataHome, "multimc"),
+			filepath.Join(home, "MultiMC"),
+			filepath.Join(home, ".var", "app", "org.multimc.MultiMC", "data", "multimc"),
+		},
+		LauncherATLauncher: {
+			filepath.Join(dataHome, "ATLauncher"),
+			filepath.Join(home, ".var", "app", "com.atlauncher.ATLauncher", "data"),
+			filepath.Join(home, "snap", "atlauncher", "current", ".local", "share", "ATLauncher"),
+		},
+	}
+}
+
+// reads key=value lines, the format of instance.cfg and the MultiMC/Prism settings files
+func readINI(path string) map[string]string {
+	values := map[string]string{}
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// MultiMC and its forks (Prism) keep instances in <root>/instances/<id>/ with the game in .minecraft or minecraft
+func mmcInstances(launcher string, root string, settingsFile string) []Instance {
+	instancesDir := filepath.Join(root, "instances")
+	if dir := readINI(filepath.Join(root, settingsFile))["InstanceDir"]; dir != "" {
+		if filepath.IsAbs(dir) {
+			instancesDir = dir
+		} else {
+			instancesDir = filepath.Join(root, dir)
+		}
+	}
+	entries, err := os.ReadDir(instancesDir)
+	if err != nil {
+		return nil
+	}
+	var instances []Instance
+	for _, entry := range entries {
+		dir := filepath.Join(instancesDir, entry.Name())
+		cfg := readINI(filepath.Join(dir, "instance.cfg"))
+		if len(cfg) == 0 {
+			continue
+		}
+		gameDir := filepath.Join(dir, ".minecraft")
+		if !isDir(gameDir) && isDir(filepath.Join(dir, "minecraft")) {
+			gameDir = filepath.Join(dir, "minecraft")
+		}
+		name := cfg["name"]
+		if name == "" {
+			name = entry.Name()
+		}
+		instances = append(instances, Instance{Launcher: launcher, ID: entry.Name(), Name: name, GameDir: gameDir, Version: mmcVersion(dir)})
+	}
+	return instances
+}
+
+// the Minecraft version of a MultiMC/Prism instance, from the net.minecraft component of mmc-pack.json
+func mmcVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "mmc-pack.json"))
+	if err != nil {
+		return ""
+	}
+	var pack struct {
+		Components []struct {
+			UID     string `json:"uid"`
+			Version string `json:"version"`
+		} `json:"components"`
+	}
+	if json.Unmarshal(data, &pack) != nil {
+		return ""
+	}
+	for _, c := range pack.Components {
+		if c.UID == "net.minecraft" {
+			return c.Version
+		}
+	}
+	return ""
+}
+
+// CurseForge and ATLauncher use the instance folder itself as the game directory and describe it in a json file
+func jsonInstances(launcher string, instancesDir string, describe func(data []byte) (string, string)) []Instance {
+	entries, err := os.ReadDir(instancesDir)
+	if err != nil {
+		return nil
+	}
+	file := "instance.json"
+	if launcher == LauncherCurseForge {
+		file = "minecraftinstance.json"
+	}
+	var instances []Instance
+	for _, entry := range entries {
+		dir := filepath.Join(instancesDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		name, version := describe(data)
+		if name == "" {
+			name = entry.Name()
+		}
+		instances = append(instances, Instance{Launcher: launcher, ID: entry.Name(), Name: name, GameDir: dir, Version: version})
+	}
+	return instances
+}
+
+func describeCurseForge(data []byte) (string, string) {
+	var info struct {
+		Name        string `json:"name"`
+		GameVersion string `json:"gameVersion"`
+	}
+	json.Unmarshal(data, &info)
+	return info.Name, info.GameVersion
+}
+
+func describeATLauncher(data []byte) (string, string) {
+	var info struct {
+		ID       string `json:"id"` // the Minecraft version
+		Launcher struct {
+			Name string `json:"name"`
+		} `json:"launcher"`
+	}
+	json.Unmarshal(data, &info)
+	return info.Launcher.Name, info.ID
+}
+
+// finds every game directory on this machine, vanilla first then each launcher's instances by name
+func findInstances(home string) []Instance {
+	var instances []Instance
+	seen := map[string]bool{}
+	add := func(found ...Instance) {
+		for _, instance := range found {
+			if seen[instance.GameDir] {
+				continue
+			}
+			seen[instance.GameDir] = true
+			instance.SavesDir = normalizePath(filepath.Join(instance.GameDir, "saves"))
+			instances = append(instances, instance)
+		}
+	}
+	roots := launcherRoots(home)
+	for _, root := range roots[LauncherVanilla] {
+		if isDir(root) {
+			add(Instance{Launcher: LauncherVanilla, Name: "Minecraft Launcher", GameDir: root})
+		}
+	}
+	var modded []Instance
+	for _, root := range roots[LauncherPrism] {
+		modded = append(modded, mmcInstances(LauncherPrism, root, "prismlauncher.cfg")...)
+	}
+	for _, root := range roots[LauncherMultiMC] {
+		modded = append(modded, mmcInstances(LauncherMultiMC, root, "multimc.cfg")...)
+	}
+	for _, root := range roots[LauncherCurseForge] {
+		modded = append(modded, jsonInstances(LauncherCurseForge, filepath.Join(root, "Instances"), describeCurseForge)...)
+	}
+	for _, root := range roots[LauncherATLauncher] {
+		modded = append(modded, jsonInstances(LauncherATLauncher, filepath.Join(root, "instances"), describeATLauncher)...)
+	}
+	sort.SliceStable(modded, func(i, j int) bool {
+		return strings.ToLower(modded[i].Name) < strings.ToLower(modded[j].Name)
+	})
+	add(modded...)
+	return instances
+}
+
+// ListInstances returns the game directories of every launcher found on this machine, so a world can be
+// picked from a specific modded instance
+func (a *App) ListInstances() ([]Instance, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return findInstances(home), nil
+}
+
+func samePath(a string, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		// both usually have case insensitive file systems
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// reports whether the world belongs to the game directory. an unknown directory (the process couldn't be
+// inspected) matches every world
+func (w WorldConfig) runsIn(gameDir string) bool {
+	return gameDir == "" || samePath(gameDir, w.gameDir())
+}
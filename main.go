@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +13,10 @@ import (
 var assets embed.FS
 
 func main() {
+	// "minevcs status", "minevcs push" and the other commands run without the window
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
 	app := NewApp()
 
 	err := wails.Run(&options.App{
@@ -0,0 +1,82 @@
+package nbt
+
+// Compound is a decoded TAG_Compound. the getters return the zero value when a key is missing or holds a
+// different type, since fields come and go between Minecraft versions and callers rarely care why one is absent
+type Compound map[string]any
+
+func (c Compound) Compound(key string) Compound {
+	v, _ := c[key].(Compound)
+	return v
+}
+
+func (c Compound) List(key string) []any {
+	v, _ := c[key].([]any)
+	return v
+}
+
+func (c Compound) String(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c Compound) Byte(key string) int8 {
+	v, _ := c[key].(int8)
+	return v
+}
+
+func (c Compound) Bool(key string) bool {
+	return c.Byte(key) != 0
+}
+
+// Int also accepts the smaller integer tags, some fields changed type over the years
+func (c Compound) Int(key string) int32 {
+	switch v := c[key].(type) {
+	case int32:
+		return v
+	case int16:
+		return int32(v)
+	case int8:
+		return int32(v)
+	}
+	return 0
+}
+
+func (c Compound) Long(key string) int64 {
+	switch v := c[key].(type) {
+	case int64:
+		return v
+	case int32, int16, int8:
+		return int64(c.Int(key))
+	}
+	return 0
+}
+
+func (c Compound) Float(key string) float32 {
+	v, _ := c[key].(float32)
+	return v
+}
+
+func (c Compound) Double(key string) float64 {
+	switch v := c[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	}
+	return 0
+}
+
+func (c Compound) IntArray(key string) []int32 {
+	v, _ := c[key].([]int32)
+	return v
+}
+
+func (c Compound) LongArray(key string) []int64 {
+	v, _ := c[key].([]int64)
+	return v
+}
+
+func (c Compound) Has(key string) bool {
+	_, ok := c[key]
+	return ok
+}
@@ -0,0 +1,120 @@
+package nbt
+
+import (
+	"fmt"
+	"time"
+)
+
+// game modes as stored in GameType / playerGameType
+var gameModes = map[int32]string{0: "survival", 1: "creative", 2: "adventure", 3: "spectator"}
+
+// Level is the part of level.dat MineVCS cares about
+type Level struct {
+	Name        string // LevelName, the name shown in the world list (not the folder name)
+	Seed        int64
+	GameMode    string
+	Hardcore    bool
+	DataVersion int32  // 0 for worlds from before 1.9
+	VersionName string // e.g. "1.20.4", empty before 1.9
+	Snapshot    bool
+	LastPlayed  time.Time
+	SpawnX      int32
+	SpawnY      int32
+	SpawnZ      int32
+	// the singleplayer player, stored inside level.dat rather than in playerdata. nil on servers
+	Player *Player
+}
+
+// Player is the part of a player's data MineVCS cares about, from level.dat or playerdata/<uuid>.dat
+type Player struct {
+	DataVersion int32
+	Position    [3]float64
+	Dimension   string // "minecraft:overworld", "minecraft:the_nether" or "minecraft:the_end" (or a modded one)
+	GameMode    string
+	Health      float32
+	XpLevel     int32
+}
+
+func gameMode(id int32) string {
+	if mode, ok := gameModes[id]; ok {
+		return mode
+	}
+	return fmt.Sprintf("unknown (%d)", id)
+}
+
+// ParseLevel reads the fields of a decoded level.dat
+func ParseLevel(root Compound) (*Level, error) {
+	data := root.Compound("Data")
+	if data == nil {
+		return nil, fmt.Errorf("%w: level.dat has no Data compound", ErrInvalid)
+	}
+	level := &Level{
+		Name:        data.String("LevelName"),
+		GameMode:    gameMode(data.Int("GameType")),
+		Hardcore:    data.Bool("hardcore"),
+		DataVersion: data.Int("DataVersion"),
+		LastPlayed:  time.UnixMilli(data.Long("LastPlayed")),
+		SpawnX:      data.Int("SpawnX"),
+		SpawnY:      data.Int("SpawnY"),
+		SpawnZ:      data.Int("SpawnZ"),
+	}
+	// 1.16 moved the seed into WorldGenSettings
+	if settings := data.Compound("WorldGenSettings"); settings.Has("seed") {
+		level.Seed = settings.Long("seed")
+	} else {
+		level.Seed = data.Long("RandomSeed")
+	}
+	if version := data.Compound("Version"); version != nil {
+		level.VersionName = version.String("Name")
+		level.Snapshot = version.Bool("Snapshot")
+	}
+	if player := data.Compound("Player"); player != nil {
+		level.Player = ParsePlayer(player)
+		if level.Player.DataVersion == 0 {
+			level.Player.DataVersion = level.DataVersion
+		}
+	}
+	return level, nil
+}
+
+// ParsePlayer reads the fields of a decoded player compound
+func ParsePlayer(c Compound) *Player {
+	player := &Player{
+		DataVersion: c.Int("DataVersion"),
+		GameMode:    gameMode(c.Int("playerGameType")),
+		Health:      c.Float("Health"),
+		XpLevel:     c.Int("XpLevel"),
+	}
+	pos := c.List("Pos")
+	for i := 0; i < len(pos) && i < 3; i++ {
+		if v, ok := pos[i].(float64); ok {
+			player.Position[i] = v
+		}
+	}
+	switch dim := c["Dimension"].(type) {
+	case string:
+		player.Dimension = dim
+	case int32:
+		// before 1.16 the dimension was a number
+		player.Dimension = map[int32]string{-1: "minecraft:the_nether", 0: "minecraft:overworld", 1: "minecraft:the_end"}[dim]
+	}
+	return player
+}
+
+// ReadLevel decodes a level.dat file
+func ReadLevel(path string) (*Level, error) {
+	root, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLevel(root)
+}
+
+// ReadPlayer decodes a playerdata/<uuid>.dat file
+func ReadPlayer(path string) (*Player, error) {
+	root, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlayer(root), nil
+}
@@ -0,0 +1,316 @@
+// Package nbt decodes the Named Binary Tag format Minecraft uses for level.dat, playerdata and region chunks.
+// every tag is turned into a plain Go value:
+//
+//	TAG_Byte       int8
+//	TAG_Short      int16
+//	TAG_Int        int32
+//	TAG_Long       int64
+//	TAG_Float      float32
+//	TAG_Double     float64
+//	TAG_Byte_Array []byte
+//	TAG_String     string
+//	TAG_List       []any
+//	TAG_Compound   Compound
+//	TAG_Int_Array  []int32
+//	TAG_Long_Array []int64
+package nbt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+const (
+	// deeper nesting than this is almost certainly a corrupt or hostile file
+	maxDepth = 512
+	// arrays are read in pieces of this many elements so a bogus length can't allocate gigabytes up front
+	readChunk = 1 << 16
+)
+
+var ErrInvalid = errors.New("invalid nbt data")
+
+// Decode reads one uncompressed NBT document and returns the name and contents of its root compound
+func Decode(r io.Reader) (string, Compound, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	tag, err := d.byte()
+	if err != nil {
+		return "", nil, err
+	}
+	if tag != TagCompound {
+		return "", nil, fmt.Errorf("%w: root tag is %d, expected a compound", ErrInvalid, tag)
+	}
+	name, err := d.string()
+	if err != nil {
+		return "", nil, err
+	}
+	root, err := d.compound(0)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, root, nil
+}
+
+// DecodeCompressed works out whether data is gzip, zlib or plain NBT from its first bytes and decodes it
+func DecodeCompressed(r io.Reader) (string, Compound, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	switch {
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer gz.Close()
+		return decodeChecked(gz)
+	case magic[0] == 0x78:
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return "", nil, err
+		}
+		defer zr.Close()
+		return decodeChecked(zr)
+	default:
+		return Decode(br)
+	}
+}
+
+// decodes a compressed stream and reads it to the end, which is when gzip and zlib check their checksum. a
+// file cut off in its trailer would otherwise decode fine
+func decodeChecked(r io.Reader) (string, Compound, error) {
+	name, root, err := Decode(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return name, root, nil
+}
+
+// ReadFile decodes a (usually gzipped) .dat file
+func ReadFile(path string) (Compound, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, root, err := DecodeCompressed(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return root, nil
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	buf [8]byte
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if _, err := io.ReadFull(d.r, d.buf[:n]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return d.buf[:n], nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) int16() (int16, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *decoder) int32() (int32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *decoder) int64() (int64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// strings are java's modified utf-8, which is plain utf-8 for everything a world name or id will contain
+func (d *decoder) string() (string, error) {
+	n, err := d.int16()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, uint16(n))
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return string(b), nil
+}
+
+func (d *decoder) length() (int, error) {
+	n, err := d.int32()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%w: negative length %d", ErrInvalid, n)
+	}
+	return int(n), nil
+}
+
+func (d *decoder) compound(depth int) (Compound, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrInvalid)
+	}
+	c := Compound{}
+	for {
+		tag, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if tag == TagEnd {
+			return c, nil
+		}
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.payload(tag, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		c[name] = v
+	}
+}
+
+func (d *decoder) payload(tag byte, depth int) (any, error) {
+	switch tag {
+	case TagByte:
+		b, err := d.byte()
+		return int8(b), err
+	case TagShort:
+		return d.int16()
+	case TagInt:
+		return d.int32()
+	case TagLong:
+		return d.int64()
+	case TagFloat:
+		v, err := d.int32()
+		return math.Float32frombits(uint32(v)), err
+	case TagDouble:
+		v, err := d.int64()
+		return math.Float64frombits(uint64(v)), err
+	case TagByteArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return buf.Bytes(), nil
+	case TagString:
+		return d.string()
+	case TagList:
+		return d.list(depth)
+	case TagCompound:
+		return d.compound(depth)
+	case TagIntArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int32, 0, min(n, readChunk))
+		for i := 0; i < n; i++ {
+			v, err := d.int32()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case TagLongArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int64, 0, min(n, readChunk))
+		for i := 0; i < n; i++ {
+			v, err := d.int64()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%w: unknown tag type %d", ErrInvalid, tag)
+}
+
+func (d *decoder) list(depth int) ([]any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrInvalid)
+	}
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	if tag == TagEnd && n > 0 {
+		return nil, fmt.Errorf("%w: list of %d end tags", ErrInvalid, n)
+	}
+	values := make([]any, 0, min(n, readChunk))
+	for i := 0; i < n; i++ {
+		v, err := d.payload(tag, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
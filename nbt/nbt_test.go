@@ -0,0 +1,289 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata holds two generated worlds laid out like the game writes them:
+//   - 1.20.4: level.dat with the singleplayer player, a second player in playerdata and a region with a zlib
+//     chunk at 0,0, a gzip chunk at 3,1, an uncompressed chunk at 7,2, an lz4 chunk at 8,8 and 5,6 stored
+//     externally in c.5.6.mcc. chunks use the 1.18 layout: sections with packed block_states long arrays
+//   - 1.12.2: level.dat with RandomSeed and a numeric player dimension, and a region whose chunks are wrapped
+//     in Level with Blocks byte arrays
+
+const (
+	fixtureLevel  = "testdata/1.20.4/level.dat"
+	fixturePlayer = "testdata/1.20.4/playerdata/b4854c94-3c6d-4787-9f5a-1fa55511d755.dat"
+	fixtureRegion = "testdata/1.20.4/region/r.0.0.mca"
+)
+
+func TestReadLevel(t *testing.T) {
+	level, err := ReadLevel(fixtureLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.Name != "Fixture World" {
+		t.Errorf("Name = %q, want Fixture World", level.Name)
+	}
+	if level.DataVersion != 3700 {
+		t.Errorf("DataVersion = %d, want 3700", level.DataVersion)
+	}
+	if level.VersionName != "1.20.4" || level.Snapshot {
+		t.Errorf("version = %q snapshot %v, want 1.20.4 release", level.VersionName, level.Snapshot)
+	}
+	if level.Seed != -4172144997902289642 {
+		t.Errorf("Seed = %d, want -4172144997902289642", level.Seed)
+	}
+	if level.GameMode != "survival" || level.Hardcore {
+		t.Errorf("game mode = %q hardcore %v, want survival", level.GameMode, level.Hardcore)
+	}
+	if level.SpawnX != -128 || level.SpawnY != 70 || level.SpawnZ != 256 {
+		t.Errorf("spawn = %d,%d,%d, want -128,70,256", level.SpawnX, level.SpawnY, level.SpawnZ)
+	}
+	if !level.LastPlayed.Equal(time.UnixMilli(1718031222482)) {
+		t.Errorf("LastPlayed = %v", level.LastPlayed)
+	}
+	if level.Player == nil {
+		t.Fatal("no singleplayer player")
+	}
+	if level.Player.Position != [3]float64{-123.69999998807907, 71, 256.3000000119209} {
+		t.Errorf("player Pos = %v", level.Player.Position)
+	}
+	if level.Player.Dimension != "minecraft:overworld" || level.Player.DataVersion != 3700 {
+		t.Errorf("player dimension %q data version %d", level.Player.Dimension, level.Player.DataVersion)
+	}
+}
+
+func TestReadLegacyLevel(t *testing.T) {
+	level, err := ReadLevel("testdata/1.12.2/level.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level.Name != "Old World" || level.DataVersion != 1343 || level.VersionName != "1.12.2" {
+		t.Errorf("level = %q data version %d %q, want Old World 1343 1.12.2", level.Name, level.DataVersion, level.VersionName)
+	}
+	if level.Seed != 8486642361424851208 {
+		t.Errorf("Seed = %d, want the RandomSeed 8486642361424851208", level.Seed)
+	}
+	if level.GameMode != "creative" || level.SpawnX != 12 || level.SpawnY != 64 || level.SpawnZ != -40 {
+		t.Errorf("game mode %q spawn %d,%d,%d", level.GameMode, level.SpawnX, level.SpawnY, level.SpawnZ)
+	}
+	if level.Player == nil {
+		t.Fatal("no singleplayer player")
+	}
+	if level.Player.Dimension != "minecraft:the_nether" || level.Player.Position != [3]float64{-3.5, 64, 8.25} {
+		t.Errorf("player in %q at %v, want the nether at -3.5,64,8.25", level.Player.Dimension, level.Player.Position)
+	}
+}
+
+func TestReadPlayer(t *testing.T) {
+	player, err := ReadPlayer(fixturePlayer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Position != [3]float64{12.5, -34, -1029.25} {
+		t.Errorf("Pos = %v, want 12.5,-34,-1029.25", player.Position)
+	}
+	if player.Dimension != "minecraft:the_nether" {
+		t.Errorf("Dimension = %q", player.Dimension)
+	}
+	if player.GameMode != "creative" || player.Health != 20 || player.XpLevel != 31 || player.DataVersion != 3700 {
+		t.Errorf("player = %+v", player)
+	}
+}
+
+func TestReadChunk(t *testing.T) {
+	for _, c := range []struct {
+		x, z int
+		how  string
+	}{{0, 0, "zlib"}, {3, 1, "gzip"}, {7, 2, "uncompressed"}, {5, 6, "external"}} {
+		chunk, err := ReadChunk(fixtureRegion, c.x, c.z)
+		if err != nil {
+			t.Fatalf("%s chunk %d,%d: %v", c.how, c.x, c.z, err)
+		}
+		if int(chunk.Int("xPos")) != c.x || int(chunk.Int("zPos")) != c.z || chunk.Int("DataVersion") != 3700 {
+			t.Errorf("%s chunk %d,%d decoded as %d,%d data version %d", c.how, c.x, c.z, chunk.Int("xPos"), chunk.Int("zPos"), chunk.Int("DataVersion"))
+		}
+		sections := chunk.List("sections")
+		if chunk.String("Status") != "minecraft:full" || len(sections) != 24 {
+			t.Fatalf("%s chunk %d,%d: status %q, %d sections", c.how, c.x, c.z, chunk.String("Status"), len(sections))
+		}
+		if motion := chunk.Compound("Heightmaps").LongArray("MOTION_BLOCKING"); len(motion) != 37 {
+			t.Errorf("%s chunk %d,%d: MOTION_BLOCKING has %d longs, want 37", c.how, c.x, c.z, len(motion))
+		}
+		bottom := sections[0].(Compound)
+		if bottom.Byte("Y") != -4 {
+			t.Errorf("%s chunk %d,%d: lowest section Y = %d, want -4", c.how, c.x, c.z, bottom.Byte("Y"))
+		}
+		states := bottom.Compound("block_states")
+		palette := states.List("palette")
+		if len(palette) != 4 || palette[2].(Compound).String("Name") != "minecraft:grass_block" {
+			t.Errorf("%s chunk %d,%d: palette = %v", c.how, c.x, c.z, palette)
+		}
+		// 4096 blocks at 4 bits each, 16 to a long. the first long holds palette indices 0,1,2,3,0,...
+		data := states.LongArray("data")
+		if len(data) != 256 || data[0] != 0x3210321032103210 {
+			t.Errorf("%s chunk %d,%d: %d packed longs, first %#x", c.how, c.x, c.z, len(data), data[0])
+		}
+	}
+	if _, err := ReadChunk(fixtureRegion, 8, 8); err == nil || !strings.Contains(err.Error(), "lz4") {
+		t.Errorf("lz4 chunk: err = %v, want lz4 not supported", err)
+	}
+	if _, err := ReadChunk(fixtureRegion, 5, 5); !errors.Is(err, ErrNoChunk) {
+		t.Errorf("ungenerated chunk: err = %v, want ErrNoChunk", err)
+	}
+	stamps, err := ChunkTimestamps(fixtureRegion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamps[0] != 1718031220 || stamps[3+1*32] != 1718031100 || stamps[5+6*32] != 1718031103 || stamps[1] != 0 {
+		t.Errorf("timestamps = %d, %d, %d, %d", stamps[0], stamps[3+1*32], stamps[5+6*32], stamps[1])
+	}
+}
+
+func TestReadLegacyChunk(t *testing.T) {
+	region := filepath.Join("testdata", "1.12.2", "region", "r.0.0.mca")
+	for _, c := range []struct{ x, z int }{{0, 0}, {31, 31}} {
+		chunk, err := ReadChunk(region, c.x, c.z)
+		if err != nil {
+			t.Fatalf("chunk %d,%d: %v", c.x, c.z, err)
+		}
+		level := chunk.Compound("Level")
+		if chunk.Int("DataVersion") != 1343 || int(level.Int("xPos")) != c.x || int(level.Int("zPos")) != c.z {
+			t.Errorf("chunk %d,%d decoded as %d,%d data version %d", c.x, c.z, level.Int("xPos"), level.Int("zPos"), chunk.Int("DataVersion"))
+		}
+		if heights := level.IntArray("HeightMap"); len(heights) != 256 {
+			t.Errorf("chunk %d,%d: HeightMap has %d ints, want 256", c.x, c.z, len(heights))
+		}
+		sections := level.List("Sections")
+		if len(sections) != 1 {
+			t.Fatalf("chunk %d,%d: %d sections, want 1", c.x, c.z, len(sections))
+		}
+		blocks, _ := sections[0].(Compound)["Blocks"].([]byte)
+		if len(blocks) != 4096 || blocks[4095] != 3 {
+			t.Errorf("chunk %d,%d: %d block ids", c.x, c.z, len(blocks))
+		}
+	}
+}
+
+// the uncompressed bytes of a gzipped fixture
+func gunzipFixture(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTruncated(t *testing.T) {
+	for _, path := range []string{fixtureLevel, fixturePlayer, "testdata/1.12.2/level.dat"} {
+		data := gunzipFixture(t, path)
+		for n := 0; n < len(data); n++ {
+			if _, _, err := Decode(bytes.NewReader(data[:n])); err == nil {
+				t.Fatalf("%s cut to %d of %d bytes decoded without an error", path, n, len(data))
+			}
+		}
+		compressed, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n := 0; n < len(compressed)-8; n++ {
+			if _, _, err := DecodeCompressed(bytes.NewReader(compressed[:n])); err == nil {
+				t.Fatalf("%s cut to %d of %d compressed bytes decoded without an error", path, n, len(compressed))
+			}
+		}
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	data := gunzipFixture(t, fixtureLevel)
+	cases := map[string][]byte{
+		"not a compound":  append([]byte{TagList}, data[1:]...),
+		"unknown tag":     {TagCompound, 0, 0, 42, 0, 1, 'x', 0},
+		"negative length": {TagCompound, 0, 0, TagIntArray, 0, 1, 'x', 0x80, 0, 0, 0, 0},
+		"huge length":     {TagCompound, 0, 0, TagLongArray, 0, 1, 'x', 0x7f, 0xff, 0xff, 0xff, 0, 0},
+		"list of ends":    {TagCompound, 0, 0, TagList, 0, 1, 'x', TagEnd, 0, 0, 0, 1, 0},
+	}
+	deep := []byte{TagCompound, 0, 0}
+	for i := 0; i <= maxDepth+1; i++ {
+		deep = append(deep, TagCompound, 0, 0)
+	}
+	cases["nested too deeply"] = deep
+	for name, input := range cases {
+		if _, _, err := Decode(bytes.NewReader(input)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	// flipped bytes may still decode, they must never panic
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		corrupted := bytes.Clone(data)
+		for j := 0; j < 1+random.Intn(4); j++ {
+			corrupted[random.Intn(len(corrupted))] = byte(random.Intn(256))
+		}
+		Decode(bytes.NewReader(corrupted))
+	}
+}
+
+func TestCorruptedRegion(t *testing.T) {
+	region, err := os.ReadFile(fixtureRegion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	write := func(data []byte) string {
+		path := filepath.Join(dir, "r.0.0.mca")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	intoHeader := bytes.Clone(region)
+	copy(intoHeader, []byte{0, 0, 1, 1})
+	if _, err := ReadChunk(write(intoHeader), 0, 0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("chunk inside the header: err = %v, want ErrInvalid", err)
+	}
+
+	pastEnd := bytes.Clone(region)
+	copy(pastEnd, []byte{0, 0, 9, 1})
+	if _, err := ReadChunk(write(pastEnd), 0, 0); err == nil {
+		t.Error("chunk past the end of the file decoded without an error")
+	}
+
+	badLength := bytes.Clone(region)
+	copy(badLength[2*sectorSize:], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := ReadChunk(write(badLength), 0, 0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("oversized chunk length: err = %v, want ErrInvalid", err)
+	}
+
+	badCompression := bytes.Clone(region)
+	badCompression[2*sectorSize+4] = 9
+	if _, err := ReadChunk(write(badCompression), 0, 0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown compression: err = %v, want ErrInvalid", err)
+	}
+
+	if _, err := ReadChunk(write(region[:2*sectorSize+100]), 0, 0); err == nil {
+		t.Error("truncated chunk decoded without an error")
+	}
+}
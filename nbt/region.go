@@ -0,0 +1,141 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// region files (region/r.<x>.<z>.mca) hold 32x32 chunks. the first 4KiB sector is a table of where each
+// chunk lives, followed by a table of timestamps, then the chunks themselves in 4KiB sectors
+const (
+	sectorSize   = 4096
+	regionWidth  = 32
+	maxChunkSize = 255 * sectorSize // the most a chunk can take up inside the region file
+
+	compressionGzip     = 1
+	compressionZlib     = 2
+	compressionNone     = 3
+	compressionLZ4      = 4
+	compressionExternal = 128 // the chunk was too big and lives in c.<x>.<z>.mcc next to the region file
+)
+
+// ErrNoChunk means the chunk hasn't been generated
+var ErrNoChunk = errors.New("chunk not generated")
+
+// ReadChunk decodes the chunk at (x, z) relative to the region, both 0-31
+func ReadChunk(regionPath string, x int, z int) (Compound, error) {
+	if x < 0 || x >= regionWidth || z < 0 || z >= regionWidth {
+		return nil, fmt.Errorf("chunk %d,%d is outside the region", x, z)
+	}
+	f, err := os.Open(regionPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var location [4]byte
+	if _, err := f.ReadAt(location[:], int64(4*(x+z*regionWidth))); err != nil {
+		if errors.Is(err, io.EOF) {
+			// empty region files are zero bytes long
+			return nil, ErrNoChunk
+		}
+		return nil, err
+	}
+	offset := int64(location[0])<<16 | int64(location[1])<<8 | int64(location[2])
+	if offset == 0 && location[3] == 0 {
+		return nil, ErrNoChunk
+	}
+	if offset < 2 {
+		return nil, fmt.Errorf("%w: chunk %d,%d points into the region header", ErrInvalid, x, z)
+	}
+
+	var header [5]byte
+	if _, err := f.ReadAt(header[:], offset*sectorSize); err != nil {
+		return nil, fmt.Errorf("%w: chunk %d,%d: %v", ErrInvalid, x, z, err)
+	}
+	length := int64(binary.BigEndian.Uint32(header[:4]))
+	compression := header[4]
+	if compression&compressionExternal != 0 {
+		var regionX, regionZ int
+		if _, err := fmt.Sscanf(filepath.Base(regionPath), "r.%d.%d.mca", &regionX, &regionZ); err != nil {
+			return nil, fmt.Errorf("chunk %d,%d is stored in an external file but %s isn't named like a region file", x, z, regionPath)
+		}
+		return ReadExternalChunk(filepath.Dir(regionPath), regionX*regionWidth+x, regionZ*regionWidth+z, compression)
+	}
+	if length < 1 || length > maxChunkSize {
+		return nil, fmt.Errorf("%w: chunk %d,%d has length %d", ErrInvalid, x, z, length)
+	}
+	payload := io.NewSectionReader(f, offset*sectorSize+5, length-1)
+	root, err := DecodeChunk(payload, compression)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %d,%d: %w", x, z, err)
+	}
+	return root, nil
+}
+
+// ReadExternalChunk decodes an oversized chunk stored in c.<chunkX>.<chunkZ>.mcc (global chunk coordinates)
+// next to its region file
+func ReadExternalChunk(regionDir string, chunkX int, chunkZ int, compression byte) (Compound, error) {
+	f, err := os.Open(filepath.Join(regionDir, fmt.Sprintf("c.%d.%d.mcc", chunkX, chunkZ)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeChunk(f, compression&^compressionExternal)
+}
+
+// DecodeChunk decodes a chunk payload compressed with the given region compression type
+func DecodeChunk(r io.Reader, compression byte) (Compound, error) {
+	var src io.Reader
+	switch compression {
+	case compressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		src = gz
+	case compressionZlib:
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		src = zr
+	case compressionNone:
+		src = r
+	case compressionLZ4:
+		return nil, fmt.Errorf("lz4 compressed chunks are not supported")
+	default:
+		return nil, fmt.Errorf("%w: unknown chunk compression %d", ErrInvalid, compression)
+	}
+	_, root, err := decodeChecked(src)
+	return root, err
+}
+
+// ChunkTimestamps returns when each chunk of a region was last saved, indexed by x + z*32. zero means the
+// chunk hasn't been generated
+func ChunkTimestamps(regionPath string) ([regionWidth * regionWidth]uint32, error) {
+	var stamps [regionWidth * regionWidth]uint32
+	f, err := os.Open(regionPath)
+	if err != nil {
+		return stamps, err
+	}
+	defer f.Close()
+	table := make([]byte, sectorSize)
+	if _, err := f.ReadAt(table, sectorSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return stamps, nil
+		}
+		return stamps, err
+	}
+	err = binary.Read(bytes.NewReader(table), binary.BigEndian, &stamps)
+	return stamps, err
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"drive/drive"
+)
+
+// how often the background worker retries queued pushes when nothing wakes it up earlier
+const outboxRetryInterval = 30 * time.Second
+
+// errQueued is returned by cloudUpload when the push couldn't happen now and was saved for later instead
+var errQueued = errors.New("push queued until Drive is reachable")
+
+// OutboxEntry is a world snapshot taken locally that still has to be uploaded
+type OutboxEntry struct {
+	ID        string `json:"id"`
+	WorldID   string `json:"worldId"`
+	WorldName string `json:"worldName"`
+	SavesDir  string `json:"savesDir"`
+	CreatedAt string `json:"createdAt"`
+	// when the first unsent snapshot of this world was taken. anything pushed to Drive after this by another
+	// machine was made without our changes, so uploading would overwrite it
+	QueuedSince string `json:"queuedSince"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"lastError"`
+	Conflict    bool   `json:"conflict"`
+	Deferred    bool   `json:"deferred"` // waiting for the push window rather than for the network
+	// the world's format and the Minecraft version that saved it, from level.dat
+	DataVersion int32  `json:"dataVersion"`
+	VersionName string `json:"versionName"`
+}
+
+// the synced world the snapshot was taken from
+func (e OutboxEntry) world() WorldConfig {
+	return WorldConfig{ID: e.WorldID, Name: e.WorldName, SavesDir: e.SavesDir}
+}
+
+// Outbox is the persistent queue of pushes that failed or happened while offline, stored in ~/.minevcs/outbox
+type Outbox struct {
+	mu      sync.Mutex
+	dir     string
+	entries []OutboxEntry
+	kick    chan struct{}
+	// ids of the snapshots being uploaded right now. their folders are left alone until the upload is over, even
+	// if a newer snapshot replaces them or they're discarded in the meantime
+	uploading map[string]bool
+}
+
+func loadOutbox(dir string) (*Outbox, error) {
+	o := &Outbox{dir: dir, kick: make(chan struct{}, 1), uploading: map[string]bool{}}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(o.indexPath())
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &o.entries); err != nil {
+		return nil, fmt.Errorf("outbox index is corrupted: %w", err)
+	}
+	// drop entries whose snapshot went missing, there's nothing left to upload for them
+	kept := o.entries[:0]
+	for _, entry := range o.entries {
+		if _, err := os.Stat(o.zipPath(entry)); err == nil {
+			kept = append(kept, entry)
+		}
+	}
+	o.entries = kept
+	return o, o.save()
+}
+
+func (o *Outbox) indexPath() string {
+	return filepath.Join(o.dir, "outbox.json")
+}
+
+func (o *Outbox) snapshotDir(id string) string {
+	return filepath.Join(o.dir, id)
+}
+
+// the zip keeps the world's name since that's the name it gets on Drive
+func (o *Outbox) zipPath(entry OutboxEntry) string {
+	return filepath.Join(o.snapshotDir(entry.ID), entry.WorldName+".zip")
+}
+
+func (o *Outbox) levelDatPath(entry OutboxEntry) string {
+	return filepath.Join(o.snapshotDir(entry.ID), levelDatName(entry.world()))
+}
+
+// the number of bytes uploading the snapshot adds to Drive
+func (o *Outbox) snapshotSize(entry OutboxEntry) int64 {
+	var total int64
+	for _, path := range []string{o.zipPath(entry), o.levelDatPath(entry)} {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// caller must hold o.mu
+func (o *Outbox) save() error {
+	data, err := json.MarshalIndent(o.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.indexPath(), data, 0600)
+}
+
+// queues a snapshot. only the newest snapshot of a world is worth uploading so older ones are dropped
+func (o *Outbox) add(entry OutboxEntry, reason error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entry.QueuedSince = entry.CreatedAt
+	entry.LastError = reason.Error()
+	entry.Deferred = errors.Is(reason, errDeferred)
+	kept := o.entries[:0]
+	for _, existing := range o.entries {
+		if existing.world().key() != entry.world().key() {
+			kept = append(kept, existing)
+			continue
+		}
+		// if the older snapshot is being uploaded this is put back to when ours was taken once it's on Drive,
+		// see finishUpload
+		entry.QueuedSince = existing.QueuedSince
+		entry.Conflict = entry.Conflict || existing.Conflict
+		if !o.uploading[existing.ID] {
+			os.RemoveAll(o.snapshotDir(existing.ID))
+		}
+	}
+	o.entries = append(kept, entry)
+	return o.save()
+}
+
+func (o *Outbox) remove(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, entry := range o.entries {
+		if entry.ID == id {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			if !o.uploading[id] {
+				os.RemoveAll(o.snapshotDir(id))
+			}
+			return o.save()
+		}
+	}
+	return fmt.Errorf("no queued push with id %s", id)
+}
+
+// marks a queued snapshot as being uploaded so its folder isn't deleted under the upload
+func (o *Outbox) startUpload(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.uploading[id] = true
+}
+
+// ends an upload started with startUpload. once the snapshot is on Drive it leaves the queue, and a newer snapshot
+// of the world queued during the upload only has to guard against pushes made after it, not against ours
+func (o *Outbox) finishUpload(entry OutboxEntry, uploaded bool) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.uploading, entry.ID)
+	now := time.Now().UTC().Format(time.RFC3339)
+	kept := o.entries[:0]
+	queued := false
+	for _, existing := range o.entries {
+		switch {
+		case existing.ID == entry.ID:
+			if uploaded {
+				continue
+			}
+			queued = true
+		case uploaded && existing.world().key() == entry.world().key():
+			existing.QueuedSince = now
+		}
+		kept = append(kept, existing)
+	}
+	o.entries = kept
+	if !queued {
+		os.RemoveAll(o.snapshotDir(entry.ID))
+	}
+	return o.save()
+}
+
+func (o *Outbox) update(id string, fn func(entry *OutboxEntry)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := range o.entries {
+		if o.entries[i].ID == id {
+			fn(&o.entries[i])
+			return o.save()
+		}
+	}
+	return fmt.Errorf("no queued push with id %s", id)
+}
+
+func (o *Outbox) get(id string) (OutboxEntry, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, entry := range o.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return OutboxEntry{}, false
+}
+
+func (o *Outbox) list() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxEntry(nil), o.entries...)
+}
+
+// reports whether the world has local progress that hasn't reached Drive yet
+func (o *Outbox) pending(w WorldConfig) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, entry := range o.entries {
+		if entry.world().key() == w.key() {
+			return true
+		}
+	}
+	return false
+}
+
+// asks the worker to try again now instead of waiting for the next tick
+func (o *Outbox) wake() {
+	select {
+	case o.kick <- struct{}{}:
+	default:
+	}
+}
+
+// zips the world and copies its level.dat into a fresh snapshot folder inside the outbox. the snapshot
+// isn't queued yet, that only happens if uploading it fails
+func (a *App) createSnapshot(w WorldConfig) (OutboxEntry, error) {
+	now := time.Now().UTC()
+	worldPath := w.path()
+	entry := OutboxEntry{
+		ID:        now.Format("20060102T150405.000000000Z"),
+		WorldID:   w.ID,
+		WorldName: w.Name,
+		SavesDir:  w.SavesDir,
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	entry.DataVersion, entry.VersionName = localWorldVersion(worldPath)
+	dir := a.outbox.snapshotDir(entry.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return entry, err
+	}
+	if _, err := a.zipFolder(worldPath, dir); err != nil {
+		os.RemoveAll(dir)
+		return entry, err
+	}
+	if err := copyFile(filepath.Join(worldPath, "level.dat"), a.outbox.levelDatPath(entry)); err != nil {
+		os.RemoveAll(dir)
+		return entry, err
+	}
+	return entry, nil
+}
+
+// uploads a snapshot: lock file first, then level.dat (used for hashing), then the zipped world, then unlock
+func (a *App) uploadSnapshot(entry OutboxEntry) error {
+	a.pushMu.Lock()
+	defer a.pushMu.Unlock()
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	ctx, srv, err := drive.InitDrive()
+	if err != nil {
+		return err
+	}
+	// snapshots queued by older versions don't carry the world's id yet
+	w, err := a.resolveWorld(entry.world())
+	if err != nil {
+		return err
+	}
+
+	// lock file logic
+	lockFilePath := filepath.Join(home, ".minevcs", "temp.lock")
+	if _, err := os.Stat(lockFilePath); os.IsNotExist(err) {
+		a.printAndEmit("Lock file not found, please restart the app")
+		return fmt.Errorf("lock file not found")
+	}
+	lockFile, err := os.Open(lockFilePath)
+	if err != nil {
+		return err
+	}
+	tempLockFile, err := drive.UploadWorldFile(ctx, srv, w.ID, w.Name, drive.KindLock, lockFile, nil)
+	lockFile.Close()
+	if err != nil {
+		return err
+	}
+	// a failed push must not leave the lock behind, otherwise every other machine refuses to pull
+	uploaded := false
+	defer func() {
+		if uploaded {
+			return
+		}
+		if err := drive.DeleteFile(srv, tempLockFile.Id); err != nil {
+			a.printAndEmit("Error removing lock file from Drive, it may need to be deleted manually: " + err.Error() + " ❌")
+		}
+	}()
+
+	// then upload the level.dat file to drive used to hash later to save time (avoiding unnecessary uploads if the world is in sync with cloud aka the user logs in but doesnt change anything in their world and quits the game)
+	levelDatFile, err := os.Open(a.outbox.levelDatPath(entry))
+	if err != nil {
+		return err
+	}
+	_, err = drive.UploadWorldFile(ctx, srv, w.ID, w.Name, drive.KindLevelDat, levelDatFile, nil)
+	levelDatFile.Close()
+	if err != nil {
+		return err
+	}
+	a.printAndEmit("Level.dat uploaded (for hashing)")
+	a.printAndEmit("PLEASE WAIT: pushing world to Drive... ⌛️")
+
+	file, err := os.Open(a.outbox.zipPath(entry))
+	if err != nil {
+		return err
+	}
+	_, err = drive.UploadWorldFile(ctx, srv, w.ID, w.Name, drive.KindWorld, file, versionProperties(entry.DataVersion, entry.VersionName))
+	file.Close()
+	if err != nil {
+		return err
+	}
+	a.printAndEmit("World uploaded successfully to Drive ✅")
+	uploaded = true
+	err = drive.DeleteFile(srv, tempLockFile.Id)
+	if err != nil {
+		a.printAndEmit("Error deleting lock file: " + err.Error() + " ❌")
+		return err
+	}
+	return nil
+}
+
+// background loop that uploads queued snapshots once Drive is reachable again
+func (a *App) runOutboxWorker() {
+	ticker := time.NewTicker(outboxRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.outbox.kick:
+		}
+		a.flushOutbox()
+	}
+}
+
+func (a *App) flushOutbox() {
+	entries := a.outbox.list()
+	if len(entries) == 0 || !drive.Reachable() {
+		return
+	}
+	now := time.Now()
+	transfer := a.transferSettings()
+	for _, entry := range entries {
+		if entry.Conflict {
+			continue
+		}
+		if transfer.shouldDefer(a.outbox.snapshotSize(entry), now) {
+			if !entry.Deferred {
+				a.outbox.update(entry.ID, func(e *OutboxEntry) { e.Deferred = true })
+				a.emitOutbox()
+			}
+			continue
+		}
+		a.pushQueued(entry.ID, false)
+	}
+}
+
+// uploads a queued snapshot. unless forced it's held back when another machine pushed the world after the first
+// unsent snapshot was taken
+func (a *App) pushQueued(id string, force bool) error {
+	entry, ok := a.outbox.get(id)
+	if !ok {
+		return fmt.Errorf("no queued push with id %s", id)
+	}
+	// the same lock a push of the world from the app holds, so the two can't upload over each other
+	lock := a.worldPushLock(entry.world())
+	lock.Lock()
+	defer lock.Unlock()
+	// a newer snapshot may have replaced it or it may have been discarded while waiting for the lock
+	current, ok := a.outbox.get(id)
+	if !ok {
+		return fmt.Errorf("queued push of %s was replaced or discarded", entry.WorldName)
+	}
+	entry = current
+	if !force {
+		conflict, err := a.cloudChangedSince(entry)
+		if err != nil {
+			a.outbox.update(entry.ID, func(e *OutboxEntry) { e.LastError = err.Error() })
+			return err
+		}
+		if conflict {
+			a.outbox.update(entry.ID, func(e *OutboxEntry) { e.Conflict = true })
+			a.printAndEmit("Queued push of " + entry.WorldName + " was not uploaded: another machine pushed a newer world while this one was offline. Keep or discard the local snapshot from the Home screen ⚠️")
+			a.emitOutbox()
+			return nil
+		}
+	}
+
+	a.printAndEmit("Uploading queued push of " + entry.WorldName + " ⏳")
+	a.outbox.startUpload(entry.ID)
+	err := a.checkQuota(a.outbox.snapshotSize(entry))
+	if err == nil {
+		err = a.uploadSnapshot(entry)
+	}
+	a.outbox.finishUpload(entry, err == nil)
+	if err != nil {
+		a.outbox.update(entry.ID, func(e *OutboxEntry) {
+			e.Attempts++
+			e.LastError = err.Error()
+		})
+		a.printAndEmit("Queued push failed, will retry later: " + err.Error() + " ❌")
+		a.emitOutbox()
+		return err
+	}
+	a.setWorldStatus(entry.world(), "ok", "Queued push uploaded")
+	a.emitOutbox()
+	return nil
+}
+
+// reports whether the world on Drive was replaced after the first queued snapshot was taken
+func (a *App) cloudChangedSince(entry OutboxEntry) (bool, error) {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return false, err
+	}
+	w, err := a.resolveWorld(entry.world())
+	if err != nil {
+		return false, err
+	}
+	zipFile, err := drive.FindWorldFile(srv, w.ID, drive.KindWorld)
+	if errors.Is(err, drive.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	uploaded, err := time.Parse(time.RFC3339, zipFile.ModifiedTime)
+	if err != nil {
+		return false, err
+	}
+	queuedSince, err := time.Parse(time.RFC3339, entry.QueuedSince)
+	if err != nil {
+		return false, err
+	}
+	return uploaded.After(queuedSince), nil
+}
+
+func (a *App) emitOutbox() {
+	a.emit("outbox", a.outbox.list())
+	a.emitWorlds()
+}
+
+func (a *App) GetQueuedPushes() []OutboxEntry {
+	if a.outbox == nil {
+		return nil
+	}
+	return a.outbox.list()
+}
+
+// uploads a queued snapshot now, even if another machine pushed in the meantime (its changes are overwritten)
+func (a *App) PushQueuedSnapshot(id string) error {
+	return a.pushQueued(id, true)
+}
+
+// throws a queued snapshot away so the world can be pulled from Drive again
+func (a *App) DiscardQueuedSnapshot(id string) error {
+	if err := a.outbox.remove(id); err != nil {
+		return err
+	}
+	a.printAndEmit("Queued push discarded")
+	a.emitOutbox()
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// a variable at the start of a path: $VAR, ${VAR} or %VAR%. the %APPDATA% style is expanded on every OS so a
+// config copied from Windows still reads
+var leadingEnvVar = regexp.MustCompile(`^(?:\$([A-Za-z_][A-Za-z0-9_]*)|\$\{([A-Za-z_][A-Za-z0-9_]*)\}|%([A-Za-z_][A-Za-z0-9_]*)%)`)
+
+// where the well known folders are when their variable isn't set, e.g. $XDG_DATA_HOME is often left unset
+func envFallback(name string, home string) string {
+	switch strings.ToUpper(name) {
+	case "HOME", "USERPROFILE":
+		return home
+	case "APPDATA":
+		return filepath.Join(home, "AppData", "Roaming")
+	case "LOCALAPPDATA":
+		return filepath.Join(home, "AppData", "Local")
+	case "XDG_DATA_HOME":
+		return filepath.Join(home, ".local", "share")
+	case "XDG_CONFIG_HOME":
+		return filepath.Join(home, ".config")
+	}
+	return ""
+}
+
+// resolvePath turns a path as the user or the config wrote it into an absolute one. it takes absolute paths,
+// ~ and ~/..., a leading $VAR, ${VAR} or %VAR%, and anything else is relative to the home directory
+func resolvePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+	lookup := func(name string) string {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			return value
+		}
+		return envFallback(name, home)
+	}
+	p = expandLeadingVar(p, lookup)
+	if p == "~" {
+		p = home
+	} else if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		p = filepath.Join(home, p[2:])
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(home, p)
+	}
+	return filepath.Clean(p)
+}
+
+// expands a variable that makes up the whole first segment of the path. a $ or % anywhere else, or a
+// variable that isn't set and has no fallback, is part of a folder's real name and left as written
+func expandLeadingVar(p string, lookup func(name string) string) string {
+	m := leadingEnvVar.FindStringSubmatch(p)
+	if m == nil {
+		return p
+	}
+	rest := p[len(m[0]):]
+	if rest != "" && rest[0] != '/' && rest[0] != '\\' {
+		return p
+	}
+	value := lookup(m[1] + m[2] + m[3])
+	if value == "" {
+		return p
+	}
+	return value + rest
+}
+
+// normalizePath is the form a folder is stored in: relative to the home directory when it's inside it, so
+// the config keeps working on a machine with another user name, and absolute otherwise
+func normalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	p = resolvePath(p)
+	if rel, ok := homeRelative(p); ok {
+		return rel
+	}
+	return p
+}
+
+// the absolute path relative to the home directory, false if it's outside it
+func homeRelative(p string) (string, bool) {
+	home, _ := os.UserHomeDir()
+	rel, err := filepath.Rel(home, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// older versions always joined a stored folder onto the home directory, even an absolute one. picks what such
+// a value actually pointed at: a leading / was meant to be inside home (the macOS default), while an absolute
+// path that already is inside home, or only exists as written, was meant as is
+func legacyPath(p string) string {
+	if p == "" || !filepath.IsAbs(p) {
+		return normalizePath(p)
+	}
+	if _, ok := homeRelative(p); ok {
+		return normalizePath(p)
+	}
+	home, _ := os.UserHomeDir()
+	joined := filepath.Join(home, p)
+	if isDir(joined) {
+		return normalizePath(joined)
+	}
+	if isDir(p) {
+		return normalizePath(p)
+	}
+	return normalizePath(joined)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	home := setTestHome(t)
+	t.Setenv("MC", "/srv/mc")
+	t.Setenv("APPDATA", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("MINEVCS_UNSET", "") // empty counts as unset
+	cases := []struct{ in, want string }{
+		{"", ""},
+		{"~", home},
+		{"~/.minecraft/saves", filepath.Join(home, ".minecraft", "saves")},
+		{`~\AppData\Roaming\.minecraft`, filepath.Join(home, `AppData\Roaming\.minecraft`)},
+		{"~alex/saves", filepath.Join(home, "~alex", "saves")},
+		{"/opt/minecraft/saves", "/opt/minecraft/saves"},
+		{".minecraft/saves", filepath.Join(home, ".minecraft", "saves")},
+		{"$MC/saves", "/srv/mc/saves"},
+		{"${MC}/saves", "/srv/mc/saves"},
+		{"$MC", "/srv/mc"},
+		{"%MC%/saves", "/srv/mc/saves"},
+		{"$HOME/.minecraft", filepath.Join(home, ".minecraft")},
+		// unset (or empty) variables with a well known location fall back to it
+		{"%APPDATA%/.minecraft/saves", filepath.Join(home, "AppData", "Roaming", ".minecraft", "saves")},
+		{"$XDG_DATA_HOME/PrismLauncher", filepath.Join(home, ".local", "share", "PrismLauncher")},
+		// anything else stays as written, it may well be a folder's real name
+		{"$MINEVCS_UNSET/saves", filepath.Join(home, "$MINEVCS_UNSET", "saves")},
+		{"${MINEVCS_UNSET}", filepath.Join(home, "${MINEVCS_UNSET}")},
+		{"$MC-old/saves", filepath.Join(home, "$MC-old", "saves")},
+		{"${MC}old", filepath.Join(home, "${MC}old")},
+		{"saves/$MC", filepath.Join(home, "saves", "$MC")},
+		{"worlds$1/saves", filepath.Join(home, "worlds$1", "saves")},
+		{"100%MC%/saves", filepath.Join(home, "100%MC%", "saves")},
+		{"$/saves", filepath.Join(home, "$", "saves")},
+		{"$1MC/saves", filepath.Join(home, "$1MC", "saves")},
+	}
+	for _, c := range cases {
+		if got := resolvePath(c.in); got != c.want {
+			t.Errorf("resolvePath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	home := setTestHome(t)
+	cases := []struct{ in, want string }{
+		{"", ""},
+		{"~", "."},
+		{filepath.Join(home, ".minecraft", "saves"), filepath.Join(".minecraft", "saves")},
+		{"~/.minecraft/saves", filepath.Join(".minecraft", "saves")},
+		{".minecraft/saves/../saves", filepath.Join(".minecraft", "saves")},
+		{"/opt/minecraft/saves", "/opt/minecraft/saves"},
+		// a sibling of home that shares its name as a prefix is outside it
+		{home + "-other/saves", home + "-other/saves"},
+	}
+	for _, c := range cases {
+		if got := normalizePath(c.in); got != c.want {
+			t.Errorf("normalizePath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestLegacyPath(t *testing.T) {
+	home := setTestHome(t)
+	mkdir := func(path string) string {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// the macOS default was stored absolute and joined onto home, so it really pointed at home/Users/...
+	doubled := "/Library/Application Support/minecraft/saves"
+	mkdir(filepath.Join(home, doubled))
+	// a folder outside home that only exists as written
+	outside := mkdir(filepath.Join(t.TempDir(), "saves"))
+	cases := []struct{ in, want string }{
+		{"", ""},
+		{".minecraft/saves", filepath.Join(".minecraft", "saves")},
+		{filepath.Join(home, ".minecraft", "saves"), filepath.Join(".minecraft", "saves")},
+		{doubled, filepath.Join("Library", "Application Support", "minecraft", "saves")},
+		{outside, outside},
+		// neither exists: older versions joined it onto home, so that's where it was
+		{"/Users/alex/minecraft/saves", filepath.Join("Users", "alex", "minecraft", "saves")},
+	}
+	for _, c := range cases {
+		if got := legacyPath(c.in); got != c.want {
+			t.Errorf("legacyPath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"drive/drive"
+)
+
+// how long the game may take to show up after its launcher was started (launcher updates, logging in, picking
+// a profile) before the launch is forgotten
+const launchGrace = 10 * time.Minute
+
+// a Play that's syncing or has just started the launcher, so the monitor knows the worlds are already
+// pulled when the game shows up
+type playLaunch struct {
+	gameDir  string
+	syncing  bool      // still pulling, the launcher hasn't been started yet
+	launched time.Time // when the launcher was started
+}
+
+// how the launchers that can start an instance directly are found
+type launcherApp struct {
+	name        string   // app name on macOS, also used in messages
+	flatpak     string   // flatpak app id
+	windowsDir  string   // folder inside %LOCALAPPDATA%\Programs the installer puts it in
+	executables []string // without .exe
+}
+
+var launcherApps = map[string]launcherApp{
+	LauncherPrism:      {"Prism Launcher", "org.prismlauncher.PrismLauncher", "PrismLauncher", []string{"prismlauncher", "PrismLauncher"}},
+	LauncherMultiMC:    {"MultiMC", "org.multimc.MultiMC", "", []string{"MultiMC", "multimc"}},
+	LauncherATLauncher: {"ATLauncher", "com.atlauncher.ATLauncher", "", []string{"ATLauncher", "atlauncher"}},
+	LauncherCurseForge: {"CurseForge", "", "CurseForge Windows", []string{"CurseForge"}},
+}
+
+// the command that starts the instance. Prism, MultiMC and ATLauncher are told which instance to launch,
+// CurseForge is only opened, and vanilla uses the launcher path from the settings
+func launchCommand(instance Instance, launcherPath string) (*exec.Cmd, error) {
+	switch instance.Launcher {
+	case LauncherPrism, LauncherMultiMC, LauncherATLauncher:
+		return launcherCommand(instance, "--launch", instance.ID)
+	case LauncherCurseForge:
+		return launcherCommand(instance)
+	}
+	if launcherPath == "" {
+		return nil, fmt.Errorf("set the Minecraft launcher path before playing")
+	}
+	if runtime.GOOS == "darwin" {
+		// the launcher has to be started as an app, running the binary inside the bundle directly doesn't work
+		if i := strings.Index(launcherPath, ".app"); i >= 0 {
+			return exec.Command("open", "-a", launcherPath[:i+len(".app")]), nil
+		}
+	}
+	if _, err := os.Stat(launcherPath); err != nil {
+		return nil, fmt.Errorf("the Minecraft launcher was not found at %s", launcherPath)
+	}
+	return exec.Command(launcherPath), nil
+}
+
+func launcherCommand(instance Instance, args ...string) (*exec.Cmd, error) {
+	app := launcherApps[instance.Launcher]
+	if app.flatpak != "" && strings.Contains(filepath.ToSlash(instance.GameDir), "/.var/app/"+app.flatpak+"/") {
+		return exec.Command("flatpak", append([]string{"run", app.flatpak}, args...)...), nil
+	}
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", append([]string{"-a", app.name, "--args"}, args...)...), nil
+	}
+	suffix := ""
+	if runtime.GOOS == "windows" {
+		suffix = ".exe"
+	}
+	// portable installs keep the executable next to the instances folder
+	var dirs []string
+	for dir := instance.GameDir; len(dirs) < 4 && filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+		dirs = append(dirs, filepath.Dir(dir))
+	}
+	if app.windowsDir != "" && os.Getenv("LOCALAPPDATA") != "" {
+		dirs = append(dirs, filepath.Join(os.Getenv("LOCALAPPDATA"), "Programs", app.windowsDir))
+	}
+	for _, dir := range dirs {
+		for _, name := range app.executables {
+			path := filepath.Join(dir, name+suffix)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return exec.Command(path, args...), nil
+			}
+		}
+	}
+	for _, name := range app.executables {
+		if path, err := exec.LookPath(name); err == nil {
+			return exec.Command(path, args...), nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find %s, start it yourself once the pull has finished", app.name)
+}
+
+// the instance a game directory belongs to, vanilla if no launcher claims it
+func instanceFor(gameDir string) Instance {
+	home, _ := os.UserHomeDir()
+	for _, instance := range findInstances(home) {
+		if samePath(instance.GameDir, gameDir) {
+			return instance
+		}
+	}
+	return Instance{Launcher: LauncherVanilla, GameDir: gameDir}
+}
+
+func gameRunningIn(gameDir string) bool {
+	games, err := findGameProcesses()
+	if err != nil {
+		return false
+	}
+	for _, game := range games {
+		if game.GameDir == "" || samePath(game.GameDir, gameDir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) beginLaunch(gameDir string) *playLaunch {
+	launch := &playLaunch{gameDir: gameDir, syncing: true}
+	a.mu.Lock()
+	a.launches = append(a.launches, launch)
+	a.mu.Unlock()
+	return launch
+}
+
+func (a *App) launchStarted(launch *playLaunch) {
+	a.mu.Lock()
+	launch.syncing = false
+	launch.launched = time.Now()
+	a.mu.Unlock()
+}
+
+func (a *App) endLaunch(launch *playLaunch) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, l := range a.launches {
+		if l == launch {
+			a.launches = append(a.launches[:i], a.launches[i+1:]...)
+			return
+		}
+	}
+}
+
+// looks up the Play a game that just started belongs to. a game started by Play is claimed so its next
+// start goes through the usual pull again. returns nil for a game started outside MineVCS
+func (a *App) claimLaunch(gameDir string) *playLaunch {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var found *playLaunch
+	kept := a.launches[:0]
+	for _, l := range a.launches {
+		if !l.syncing && time.Since(l.launched) > launchGrace {
+			continue
+		}
+		if found == nil && (gameDir == "" || samePath(gameDir, l.gameDir)) {
+			found = &playLaunch{gameDir: l.gameDir, syncing: l.syncing, launched: l.launched}
+			if !l.syncing {
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	a.launches = kept
+	return found
+}
+
+// errPlayNotSignedIn is returned by Play when there's no sign in to pull with, so the frontend can ask before
+// starting the game with whatever worlds are on this machine
+var errPlayNotSignedIn = errors.New("not signed in to Google Drive, worlds were not pulled")
+
+// Play pulls every world of the game folder and only then starts its launcher, so the game can't open a
+// world while it's being replaced. savesDir picks the instance, empty means the one of the first synced world.
+// when signed out it refuses with errPlayNotSignedIn unless withoutPull says to start anyway
+func (a *App) Play(savesDir string, withoutPull bool) error {
+	if !a.playMu.TryLock() {
+		return fmt.Errorf("already getting Minecraft ready")
+	}
+	defer a.playMu.Unlock()
+	if savesDir == "" {
+		worlds := a.worldList()
+		if len(worlds) == 0 {
+			return fmt.Errorf("add a world to sync before playing")
+		}
+		savesDir = worlds[0].SavesDir
+	}
+	gameDir := filepath.Dir(savesPath(savesDir))
+	if gameRunningIn(gameDir) {
+		return fmt.Errorf("Minecraft is already running")
+	}
+	a.mu.Lock()
+	launcherPath := resolvePath(a.minecraftLauncher)
+	a.mu.Unlock()
+	cmd, err := launchCommand(instanceFor(gameDir), launcherPath)
+	if err != nil {
+		return err
+	}
+
+	launch := a.beginLaunch(gameDir)
+	authenticated, err := a.CheckIfAuthenticated()
+	switch {
+	case err != nil || !authenticated:
+		a.setSyncStatus("error", "Not signed in, worlds were not pulled")
+		if !withoutPull {
+			a.printAndEmit("Not signed in, worlds were not pulled ⚠️")
+			a.endLaunch(launch)
+			return errPlayNotSignedIn
+		}
+		a.printAndEmit("Not signed in, starting Minecraft without pulling ⚠️")
+	case !drive.Reachable():
+		a.printAndEmit("Drive can't be reached, starting Minecraft without pulling ⚠️")
+	default:
+		a.printAndEmit("Syncing before starting Minecraft ⌛️")
+		for _, w := range a.worldList() {
+			if !w.canPull() || !w.runsIn(gameDir) {
+				continue
+			}
+			if err := a.pullIfBehind(w); err != nil {
+				a.endLaunch(launch)
+				return fmt.Errorf("%s could not be pulled, not starting Minecraft: %w", w.Name, err)
+			}
+		}
+		if a.profileRunsIn(gameDir) {
+			a.pullProfile()
+		}
+	}
+	if gameRunningIn(gameDir) {
+		// started outside MineVCS while we were pulling, the monitor has warned about it
+		a.endLaunch(launch)
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		a.endLaunch(launch)
+		return fmt.Errorf("unable to start the launcher: %w", err)
+	}
+	go cmd.Wait()
+	a.launchStarted(launch)
+	a.printAndEmit("Worlds are up to date, starting Minecraft 🎮")
+	return nil
+}
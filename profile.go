@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"drive/drive"
+)
+
+// profile sync items, the game settings and packs that can be synced next to the worlds
+const (
+	ProfileOptions       = "options"
+	ProfileServers       = "servers"
+	ProfileResourcePacks = "resourcepacks"
+	ProfileShaderPacks   = "shaderpacks"
+)
+
+// where each item lives inside the game directory
+var profileItemPaths = map[string]string{
+	ProfileOptions:       "options.txt",
+	ProfileServers:       "servers.dat",
+	ProfileResourcePacks: "resourcepacks",
+	ProfileShaderPacks:   "shaderpacks",
+}
+
+// options.txt keys that depend on the machine's screen or GPU, kept local unless the user says otherwise
+var defaultLocalOptions = []string{
+	"fullscreen", "fullscreenResolution", "overrideWidth", "overrideHeight", "guiScale",
+	"graphicsMode", "renderDistance", "simulationDistance", "maxFps", "enableVsync",
+}
+
+// ProfileSettings picks which profile items are synced from this machine
+type ProfileSettings struct {
+	Items   []string `json:"items"`
+	GameDir string   `json:"gameDir"` // stored like a world's saves folder, empty means the one holding the first synced world
+	// options.txt keys this machine never takes from or gives to other machines. nil means the defaults
+	LocalOptions []string `json:"localOptions"`
+}
+
+func (s ProfileSettings) validate() error {
+	for _, item := range s.Items {
+		if _, ok := profileItemPaths[item]; !ok {
+			return fmt.Errorf("unknown profile item %q", item)
+		}
+	}
+	for _, key := range s.LocalOptions {
+		if key == "" || strings.ContainsAny(key, ":\n") {
+			return fmt.Errorf("%q is not an options.txt key", key)
+		}
+	}
+	return nil
+}
+
+func (s ProfileSettings) localOptions() map[string]bool {
+	keys := s.LocalOptions
+	if keys == nil {
+		keys = defaultLocalOptions
+	}
+	local := map[string]bool{}
+	for _, key := range keys {
+		local[key] = true
+	}
+	return local
+}
+
+// each item is its own object in the profile folder on Drive
+func profileKind(item string) string {
+	return "profile-" + item
+}
+
+func (a *App) profileGameDir() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.profile.GameDir != "" {
+		return savesPath(a.profile.GameDir), nil
+	}
+	if len(a.worlds) == 0 {
+		return "", fmt.Errorf("add a world or set the game folder before syncing settings")
+	}
+	return a.worlds[0].gameDir(), nil
+}
+
+// reports whether profile sync applies to the game directory, empty meaning unknown
+func (a *App) profileRunsIn(gameDir string) bool {
+	dir, err := a.profileGameDir()
+	return err == nil && (gameDir == "" || samePath(gameDir, dir))
+}
+
+// the options.txt key of a "key:value" line
+func optionKey(line string) string {
+	key, _, _ := strings.Cut(line, ":")
+	return key
+}
+
+// options.txt without the lines this machine keeps to itself, so two machines only differ when a shared
+// setting changed
+func normalizeOptions(data []byte, local map[string]bool) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if !local[optionKey(scanner.Text())] {
+			out.WriteString(scanner.Text() + "\n")
+		}
+	}
+	return out.Bytes()
+}
+
+// takes every shared setting from the pulled options.txt and every local one from the current file
+func mergeOptions(pulled []byte, current []byte, local map[string]bool) []byte {
+	kept := map[string]string{}
+	var order []string
+	scanner := bufio.NewScanner(bytes.NewReader(current))
+	for scanner.Scan() {
+		key := optionKey(scanner.Text())
+		if local[key] {
+			kept[key] = scanner.Text()
+			order = append(order, key)
+		}
+	}
+	var out bytes.Buffer
+	scanner = bufio.NewScanner(bytes.NewReader(pulled))
+	for scanner.Scan() {
+		key := optionKey(scanner.Text())
+		line, ok := kept[key]
+		if !ok {
+			line = scanner.Text()
+		}
+		delete(kept, key)
+		out.WriteString(line + "\n")
+	}
+	for _, key := range order {
+		if line, ok := kept[key]; ok {
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.Bytes()
+}
+
+// hashes an item's contents, "" if it doesn't exist on this machine
+func profileItemHash(gameDir string, item string, local map[string]bool) (string, error) {
+	itemPath := filepath.Join(gameDir, profileItemPaths[item])
+	if item == ProfileOptions {
+		data, err := os.ReadFile(itemPath)
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%x", sha256.Sum256(normalizeOptions(data, local))), nil
+	}
+	if _, err := os.Stat(itemPath); os.IsNotExist(err) {
+		return "", nil
+	}
+	var paths []string
+	err := filepath.Walk(itemPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, path := range paths {
+		rel, _ := filepath.Rel(gameDir, path)
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%x\n", filepath.ToSlash(rel), fileHash.Sum(nil))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// zips an item into stageDir/<item>.zip. single files are put in a folder of their own first so every item
+// goes through the same archive format (and manifest) as worlds
+func (a *App) stageProfileItem(gameDir string, item string, stageDir string) (string, error) {
+	itemPath := filepath.Join(gameDir, profileItemPaths[item])
+	if item == ProfileOptions || item == ProfileServers {
+		folder := filepath.Join(stageDir, item)
+		if err := os.MkdirAll(folder, 0700); err != nil {
+			return "", err
+		}
+		if err := copyFile(itemPath, filepath.Join(folder, profileItemPaths[item])); err != nil {
+			return "", err
+		}
+		itemPath = folder
+	}
+	return a.zipFolder(itemPath, stageDir)
+}
+
+// pushes every enabled profile item that changed since it was last pushed
+func (a *App) pushProfile() {
+	a.mu.Lock()
+	settings := a.profile
+	a.mu.Unlock()
+	if len(settings.Items) == 0 {
+		return
+	}
+	if err := a.syncProfile(settings, true); err != nil {
+		a.printAndEmit("Settings push failed: " + err.Error() + " ❌")
+		a.setSyncStatus("error", "Settings push failed: "+err.Error())
+	}
+}
+
+// pulls every enabled profile item that differs from the copy on Drive
+func (a *App) pullProfile() {
+	a.mu.Lock()
+	settings := a.profile
+	a.mu.Unlock()
+	if len(settings.Items) == 0 {
+		return
+	}
+	if err := a.syncProfile(settings, false); err != nil {
+		a.printAndEmit("Settings pull failed: " + err.Error() + " ❌")
+		a.setSyncStatus("error", "Settings pull failed: "+err.Error())
+	}
+}
+
+// what an item hashed to, on this machine and on Drive, the last time the two agreed. a hash that moved away
+// from it means that side changed since
+type profileItemState struct {
+	LocalHash string `json:"localHash"`
+	CloudHash string `json:"cloudHash"`
+}
+
+// ProfileConflict is an item that changed both here and on Drive since they last agreed. neither copy is
+// touched until the user keeps one with ResolveProfileConflict
+type ProfileConflict struct {
+	Item string `json:"item"`
+	Path string `json:"path"` // inside the game folder, e.g. options.txt
+}
+
+// kept in ~/.minevcs/profile.json, for the game folder it was recorded in
+type profileState struct {
+	GameDir   string                      `json:"gameDir"`
+	Items     map[string]profileItemState `json:"items"`
+	Conflicts []string                    `json:"conflicts"`
+}
+
+func profileStatePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".minevcs", "profile.json")
+}
+
+// the recorded state for the game folder. anything recorded for another folder says nothing about this one
+func loadProfileState(gameDir string) *profileState {
+	state := &profileState{}
+	if data, err := os.ReadFile(profileStatePath()); err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			println("Profile sync state is corrupted, starting over:", err.Error())
+			state = &profileState{}
+		}
+	}
+	if !samePath(state.GameDir, gameDir) {
+		state = &profileState{GameDir: gameDir}
+	}
+	if state.Items == nil {
+		state.Items = map[string]profileItemState{}
+	}
+	return state
+}
+
+func (s *profileState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(profileStatePath(), data, 0600)
+}
+
+func (s *profileState) setConflict(item string, conflict bool) {
+	kept := s.Conflicts[:0]
+	for _, c := range s.Conflicts {
+		if c != item {
+			kept = append(kept, c)
+		}
+	}
+	if conflict {
+		kept = append(kept, item)
+	}
+	s.Conflicts = kept
+}
+
+// errProfileConflict is returned by syncProfileItem when both sides changed
+var errProfileConflict = errors.New("changed both on this machine and on Drive")
+
+func (a *App) syncProfile(settings ProfileSettings, push bool) error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	gameDir, err := a.profileGameDir()
+	if err != nil {
+		return err
+	}
+	state := loadProfileState(gameDir)
+	defer a.emit("profileConflicts", conflictList(state))
+	var conflicts []string
+	for _, item := range settings.Items {
+		err := a.syncProfileItem(gameDir, item, settings.localOptions(), state, push, false)
+		if errors.Is(err, errProfileConflict) {
+			conflicts = append(conflicts, profileItemPaths[item])
+			continue
+		}
+		if err != nil {
+			state.save()
+			return fmt.Errorf("%s: %w", item, err)
+		}
+	}
+	if err := state.save(); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		a.printAndEmit(strings.Join(conflicts, ", ") + " changed both on this machine and on Drive, neither was overwritten. Keep one of them from Settings & packs sync ⚠️")
+		a.setSyncStatus("error", "Settings conflict: "+strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// pushes or pulls one item, when only the side it comes from changed since the last sync. force skips that
+// check, it's how a conflict is resolved. the caller holds profileMu
+func (a *App) syncProfileItem(gameDir string, item string, local map[string]bool, state *profileState, push bool, force bool) error {
+	hash, err := profileItemHash(gameDir, item, local)
+	if err != nil {
+		return err
+	}
+	ctx, srv, err := drive.InitDrive()
+	if err != nil {
+		return err
+	}
+	cloudFile, err := drive.FindWorldFile(srv, drive.ProfileID, profileKind(item))
+	if err != nil && !errors.Is(err, drive.ErrNotFound) {
+		return err
+	}
+	cloudHash := ""
+	if cloudFile != nil {
+		cloudHash = cloudFile.AppProperties["contentHash"]
+	}
+	if hash == cloudHash {
+		if hash != "" {
+			state.Items[item] = profileItemState{LocalHash: hash, CloudHash: cloudHash}
+		}
+		state.setConflict(item, false)
+		return nil
+	}
+	// with nothing recorded yet both sides count as changed, so two different copies are a conflict rather
+	// than a guess
+	last, known := state.Items[item]
+	localChanged := !known || hash != last.LocalHash
+	cloudChanged := !known || cloudHash != last.CloudHash
+
+	// staged inside ~/.minevcs rather than the temp dir so pulled folders can be renamed into the game folder
+	home, _ := os.UserHomeDir()
+	stageDir, err := os.MkdirTemp(filepath.Join(home, ".minevcs"), "profile-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	if push {
+		if hash == "" {
+			return nil // nothing to push from this machine
+		}
+		if !force && cloudHash != "" {
+			if !localChanged {
+				return nil // only Drive changed, the next pull brings it here
+			}
+			if cloudChanged {
+				state.setConflict(item, true)
+				return errProfileConflict
+			}
+		}
+		zipPath, err := a.stageProfileItem(gameDir, item, stageDir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(zipPath); err == nil {
+			if err := a.checkQuota(info.Size()); err != nil {
+				return err
+			}
+		}
+		f, err := os.Open(zipPath)
+		if err != nil {
+			return err
+		}
+		a.pushMu.Lock()
+		_, err = drive.UploadWorldFile(ctx, srv, drive.ProfileID, "Profile", profileKind(item), f, map[string]string{"contentHash": hash})
+		a.pushMu.Unlock()
+		f.Close()
+		if err != nil {
+			return err
+		}
+		state.Items[item] = profileItemState{LocalHash: hash, CloudHash: hash}
+		state.setConflict(item, false)
+		a.printAndEmit("Pushed " + profileItemPaths[item] + " to Drive ✅")
+		return nil
+	}
+
+	if cloudFile == nil {
+		return nil // never pushed from any machine
+	}
+	if !force && hash != "" {
+		if !cloudChanged {
+			return nil // only this machine changed, the next push takes it to Drive
+		}
+		if localChanged {
+			state.setConflict(item, true)
+			return errProfileConflict
+		}
+	}
+	zipPath := filepath.Join(stageDir, item+".zip")
+	if err := drive.DownloadFile(ctx, srv, cloudFile.Id, zipPath); err != nil {
+		return err
+	}
+	extractDir, err := a.unzipFolder(zipPath, false)
+	if err != nil {
+		return fmt.Errorf("failed verification: %w", err)
+	}
+	if err := a.applyProfileItem(gameDir, item, extractDir, local); err != nil {
+		return err
+	}
+	// options.txt is merged with this machine's own settings, so what it hashes to now is what to compare with
+	if hash, err = profileItemHash(gameDir, item, local); err != nil {
+		return err
+	}
+	state.Items[item] = profileItemState{LocalHash: hash, CloudHash: cloudHash}
+	state.setConflict(item, false)
+	a.printAndEmit("Pulled " + profileItemPaths[item] + " from Drive ✅")
+	return nil
+}
+
+func conflictList(state *profileState) []ProfileConflict {
+	conflicts := []ProfileConflict{}
+	for _, item := range state.Conflicts {
+		conflicts = append(conflicts, ProfileConflict{Item: item, Path: profileItemPaths[item]})
+	}
+	return conflicts
+}
+
+// GetProfileConflicts lists the items that changed both here and on Drive
+func (a *App) GetProfileConflicts() ([]ProfileConflict, error) {
+	gameDir, err := a.profileGameDir()
+	if err != nil {
+		return []ProfileConflict{}, nil
+	}
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	return conflictList(loadProfileState(gameDir)), nil
+}
+
+// ResolveProfileConflict keeps one copy of an item that changed on both sides: this machine's is pushed over
+// the one on Drive, or Drive's is pulled over this machine's
+func (a *App) ResolveProfileConflict(item string, keepLocal bool) error {
+	if _, ok := profileItemPaths[item]; !ok {
+		return fmt.Errorf("unknown profile item %q", item)
+	}
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+	gameDir, err := a.profileGameDir()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	local := a.profile.localOptions()
+	a.mu.Unlock()
+	state := loadProfileState(gameDir)
+	defer a.emit("profileConflicts", conflictList(state))
+	if err := a.syncProfileItem(gameDir, item, local, state, keepLocal, true); err != nil {
+		state.save()
+		return err
+	}
+	return state.save()
+}
+
+// puts a pulled item in place. options.txt is merged so the machine's own settings survive, folders are
+// swapped in whole
+func (a *App) applyProfileItem(gameDir string, item string, extractDir string, local map[string]bool) error {
+	target := filepath.Join(gameDir, profileItemPaths[item])
+	switch item {
+	case ProfileOptions:
+		pulled, err := os.ReadFile(filepath.Join(extractDir, profileItemPaths[item]))
+		if err != nil {
+			return err
+		}
+		current, err := os.ReadFile(target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.WriteFile(target, mergeOptions(pulled, current, local), 0644)
+	case ProfileServers:
+		return copyFile(filepath.Join(extractDir, profileItemPaths[item]), target)
+	}
+	old := target + ".minevcs-old"
+	os.RemoveAll(old)
+	if _, err := os.Stat(target); err == nil {
+		if err := os.Rename(target, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(extractDir, target); err != nil {
+		// put the old folder back rather than leave the game without one
+		os.Rename(old, target)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+func (a *App) GetProfileSettings() ProfileSettings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	settings := a.profile
+	if settings.LocalOptions == nil {
+		settings.LocalOptions = defaultLocalOptions
+	}
+	return settings
+}
+
+func (a *App) SaveProfileSettings(settings ProfileSettings) error {
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	settings.GameDir = normalizePath(settings.GameDir)
+	a.mu.Lock()
+	a.profile = settings
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.printAndEmit("Profile sync settings saved ✅")
+	return nil
+}
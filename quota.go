@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"drive/drive"
+)
+
+// a push is allowed but warned about once it would use up more than this share of the free space
+const quotaWarnRatio = 0.8
+
+// StorageInfo is the Drive quota shown on the Home screen (all sizes in bytes)
+type StorageInfo struct {
+	Limit        int64 `json:"limit"` // 0 means the account has no limit
+	Usage        int64 `json:"usage"`
+	MinevcsUsage int64 `json:"minevcsUsage"`
+}
+
+// quotaError means a push was blocked because Drive doesn't have room for it. retrying won't help so it isn't queued
+type quotaError struct {
+	needed int64
+	free   int64
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("not enough Google Drive storage: this push needs about %s but only %s is free. Free up space in Drive and try again", formatBytes(e.needed), formatBytes(e.free))
+}
+
+// sums the size of every regular file in the world. region files are already compressed so the zip
+// ends up close to this, which makes it a good (slightly pessimistic) estimate of the upload size
+func estimateArchiveSize(worldPath string) (int64, error) {
+	var total int64
+	err := filepath.Walk(worldPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// checks that Drive has room for an upload of the given size, warning when it's getting close.
+// the old copy is only deleted after the new one is uploaded so the whole size has to fit
+func (a *App) checkQuota(size int64) error {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return err
+	}
+	quota, err := drive.GetStorageQuota(srv)
+	if err != nil {
+		return err
+	}
+	if quota.Limit == 0 {
+		return nil
+	}
+	free := quota.Limit - quota.Usage
+	if size > free {
+		return &quotaError{needed: size, free: free}
+	}
+	if float64(size) > float64(free)*quotaWarnRatio {
+		a.printAndEmit(fmt.Sprintf("Google Drive is almost full: this push uses about %s of the %s left ⚠️", formatBytes(size), formatBytes(free)))
+	}
+	return nil
+}
+
+// estimates the push size from the world tree and checks it against the quota before anything is zipped
+func (a *App) preflightPush(worldPath string) error {
+	size, err := estimateArchiveSize(worldPath)
+	if err != nil {
+		return err
+	}
+	return a.checkQuota(size)
+}
+
+func (a *App) GetStorageInfo() (StorageInfo, error) {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return StorageInfo{}, err
+	}
+	quota, err := drive.GetStorageQuota(srv)
+	if err != nil {
+		return StorageInfo{}, err
+	}
+	info := StorageInfo{Limit: quota.Limit, Usage: quota.Usage}
+	info.MinevcsUsage, err = drive.GetAppUsage(srv)
+	if err != nil {
+		return StorageInfo{}, err
+	}
+	return info, nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
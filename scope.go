@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"drive/drive"
+)
+
+// the worlds that were on Drive right before full access was given up, checked again once signed in with
+// limited access
+func scopeMigrationPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".minevcs", "scope-migration.json")
+}
+
+// NarrowDriveAccess swaps a sign in with full access to the Drive (given to older versions) for one limited
+// to the files MineVCS created. everything MineVCS uploaded stays reachable. returns the URL to sign in again
+func (a *App) NarrowDriveAccess() (string, error) {
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return "", err
+	}
+	// worlds still only in the root of the Drive are moved into the MineVCS folder while they can be found
+	for _, w := range a.worldList() {
+		if _, err := a.resolveWorld(w); err != nil {
+			a.printAndEmit("Could not link " + w.Name + " to its folder on Drive: " + err.Error() + " ⚠️")
+		}
+	}
+	worlds, err := drive.ListCloudWorlds(srv)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(worlds)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(scopeMigrationPath(), data, 0600); err != nil {
+		return "", err
+	}
+	// revoking drops the full access grant, signing in again only adds the narrower one back
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := drive.RevokeToken(ctx); err != nil {
+		return "", err
+	}
+	a.printAndEmit("Full Drive access revoked, sign in again to give MineVCS access to its own files only 🔒")
+	return a.GoogleAuth()
+}
+
+// after signing in again with limited access, warns about any world that was on Drive before and can't be
+// seen any more (uploaded by something other than MineVCS)
+func (a *App) checkScopeMigration() {
+	data, err := os.ReadFile(scopeMigrationPath())
+	if err != nil {
+		return
+	}
+	var before []drive.CloudWorld
+	if err := json.Unmarshal(data, &before); err != nil {
+		os.Remove(scopeMigrationPath())
+		return
+	}
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return
+	}
+	after, err := drive.ListCloudWorlds(srv)
+	if err != nil {
+		return // tried again next time
+	}
+	visible := map[string]bool{}
+	for _, w := range after {
+		visible[w.ID] = true
+	}
+	missing := 0
+	for _, w := range before {
+		if !visible[w.ID] {
+			missing++
+			a.printAndEmit(w.Name + " can't be seen with limited access, push it again from a machine that has it ⚠️")
+		}
+	}
+	if missing == 0 {
+		a.printAndEmit("All worlds are reachable with limited Drive access ✅")
+	}
+	os.Remove(scopeMigrationPath())
+	a.emitWorlds()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"drive/drive"
+)
+
+// errDeferred is returned by cloudUpload when a large push was snapshotted but left for the push window
+var errDeferred = errors.New("large push postponed until the push window")
+
+// TransferSettings limit how hard MineVCS uses the network. zero values mean no limit / never defer
+type TransferSettings struct {
+	UploadLimitKBps   int64  `json:"uploadLimitKBps"`
+	DownloadLimitKBps int64  `json:"downloadLimitKBps"`
+	DeferPushesOverMB int64  `json:"deferPushesOverMB"`
+	PushWindowStart   string `json:"pushWindowStart"` // "HH:MM" local time, e.g. "01:00"
+	PushWindowEnd     string `json:"pushWindowEnd"`   // may be earlier than the start to wrap past midnight
+	// while a world is open it's pushed this often, once its files haven't changed for QuietSeconds
+	// (Minecraft's autosave has finished). 0 turns periodic pushes off
+	PeriodicPushMinutes int64 `json:"periodicPushMinutes"`
+	QuietSeconds        int64 `json:"quietSeconds"`
+}
+
+// quiet period used when none is configured
+const defaultQuietSeconds = 30
+
+func (s TransferSettings) quietPeriod() time.Duration {
+	if s.QuietSeconds <= 0 {
+		return defaultQuietSeconds * time.Second
+	}
+	return time.Duration(s.QuietSeconds) * time.Second
+}
+
+// reads the settings from the flat config format used before multiple worlds could be synced
+func transferSettingsFromConfig(config map[string]string) TransferSettings {
+	parse := func(key string) int64 {
+		n, _ := strconv.ParseInt(config[key], 10, 64)
+		return n
+	}
+	return TransferSettings{
+		UploadLimitKBps:   parse("uploadLimitKBps"),
+		DownloadLimitKBps: parse("downloadLimitKBps"),
+		DeferPushesOverMB: parse("deferPushesOverMB"),
+		PushWindowStart:   config["pushWindowStart"],
+		PushWindowEnd:     config["pushWindowEnd"],
+	}
+}
+
+func (s TransferSettings) validate() error {
+	if s.UploadLimitKBps < 0 || s.DownloadLimitKBps < 0 || s.DeferPushesOverMB < 0 {
+		return fmt.Errorf("limits can't be negative")
+	}
+	if s.PeriodicPushMinutes < 0 || s.QuietSeconds < 0 {
+		return fmt.Errorf("periodic push interval and quiet period can't be negative")
+	}
+	if s.PeriodicPushMinutes > 0 && s.PeriodicPushMinutes < 5 {
+		return fmt.Errorf("periodic pushes can't run more often than every 5 minutes")
+	}
+	if s.DeferPushesOverMB == 0 {
+		return nil
+	}
+	if _, err := time.Parse("15:04", s.PushWindowStart); err != nil {
+		return fmt.Errorf("push window start must look like 01:00")
+	}
+	if _, err := time.Parse("15:04", s.PushWindowEnd); err != nil {
+		return fmt.Errorf("push window end must look like 06:00")
+	}
+	return nil
+}
+
+// reports whether now falls inside the push window
+func (s TransferSettings) inPushWindow(now time.Time) bool {
+	start, err1 := time.Parse("15:04", s.PushWindowStart)
+	end, err2 := time.Parse("15:04", s.PushWindowEnd)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from <= to {
+		return minutes >= from && minutes < to
+	}
+	return minutes >= from || minutes < to // e.g. 23:00 - 06:00
+}
+
+// reports whether a push of the given size has to wait for the push window
+func (s TransferSettings) shouldDefer(size int64, now time.Time) bool {
+	return s.DeferPushesOverMB > 0 && size > s.DeferPushesOverMB<<20 && !s.inPushWindow(now)
+}
+
+func (a *App) applyTransferSettings() {
+	settings := a.transferSettings()
+	drive.UploadLimiter.SetRate(settings.UploadLimitKBps << 10)
+	drive.DownloadLimiter.SetRate(settings.DownloadLimitKBps << 10)
+}
+
+// a copy of the transfer settings, which the outbox worker and the watcher read while the bindings change them
+func (a *App) transferSettings() TransferSettings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.transfer
+}
+
+func (a *App) GetTransferSettings() TransferSettings {
+	return a.transferSettings()
+}
+
+func (a *App) SaveTransferSettings(settings TransferSettings) error {
+	if err := settings.validate(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.transfer = settings
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.applyTransferSettings()
+	a.printAndEmit("Transfer settings saved ✅")
+	// a wider window or a higher threshold may mean something queued can go now
+	a.outbox.wake()
+	return nil
+}
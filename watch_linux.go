@@ -0,0 +1,135 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// only changes made by writers. opens and read-only closes are left out, MineVCS itself reads the world
+// (hashing, zipping, checking session.lock) and would otherwise wake itself up forever
+const watchMask = unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF
+
+// watches the folders and everything below them with inotify and calls notify with the path of every change
+// until ctx is done. an empty path means events were dropped and everything should be rechecked
+func watchDirs(ctx context.Context, roots []string, notify func(path string)) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	watches := map[int]string{}
+	add := func(root string) {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if wd, err := unix.InotifyAddWatch(fd, path, watchMask); err == nil {
+				watches[wd] = path
+			}
+			return nil
+		})
+	}
+	for _, root := range roots {
+		add(root)
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		// wake up every second so a cancelled ctx is noticed
+		n, err := unix.Poll([]unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}, 1000)
+		if errors.Is(err, unix.EINTR) || n == 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		n, err = unix.Read(fd, buf)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				notify("")
+				continue
+			}
+			if event.Mask&unix.IN_IGNORED != 0 {
+				delete(watches, int(event.Wd))
+				continue
+			}
+			dir, ok := watches[int(event.Wd)]
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+				add(path)
+			}
+			notify(path)
+		}
+	}
+}
+
+// what the game directories are watched for: the game starts its log, logs/latest.log, before anything else
+const gameDirMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_MOVED_TO
+
+// blocks until a game may have started or exited: the log in one of the game directories is written to, or
+// one of the running games exits (followed through a pidfd, so there's no process table to poll). returns after
+// timeout either way, and errWatchUnsupported when the kernel can't follow a process
+func waitForGames(ctx context.Context, gameDirs []string, pids []int32, timeout time.Duration) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	for _, dir := range gameDirs {
+		// the game directory itself for a logs folder that doesn't exist yet
+		unix.InotifyAddWatch(fd, dir, gameDirMask)
+		unix.InotifyAddWatch(fd, filepath.Join(dir, "logs"), gameDirMask)
+	}
+
+	polls := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	for _, pid := range pids {
+		pidfd, err := unix.PidfdOpen(int(pid), 0)
+		if errors.Is(err, unix.ESRCH) {
+			return nil // already gone
+		}
+		if err != nil {
+			return errWatchUnsupported
+		}
+		defer unix.Close(pidfd)
+		polls = append(polls, unix.PollFd{Fd: int32(pidfd), Events: unix.POLLIN})
+	}
+
+	deadline := time.Now().Add(timeout)
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		// wake up every second so a cancelled ctx is noticed
+		n, err := unix.Poll(polls, 1000)
+		if errors.Is(err, unix.EINTR) || n == 0 {
+			continue
+		}
+		return err
+	}
+	return nil
+}
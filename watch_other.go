@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// there's no native watcher on this platform yet, the world watcher falls back to polling
+func watchDirs(ctx context.Context, roots []string, notify func(path string)) error {
+	return errWatchUnsupported
+}
+
+// without a watcher the game monitor polls the process table instead
+func waitForGames(ctx context.Context, gameDirs []string, pids []int32, timeout time.Duration) error {
+	return errWatchUnsupported
+}
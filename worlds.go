@@ -0,0 +1,366 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"drive/drive"
+
+	gdrive "google.golang.org/api/drive/v3"
+)
+
+// what MineVCS does with a world on this machine
+const (
+	PolicySync   = "sync"   // pull when Minecraft starts, push when it exits
+	PolicyPush   = "push"   // only ever upload from this machine
+	PolicyPull   = "pull"   // only ever download to this machine
+	PolicyPaused = "paused" // leave the world alone for now
+)
+
+// WorldConfig is one synced world as stored in the config file
+type WorldConfig struct {
+	ID       string `json:"id,omitempty"` // identifies the world on Drive, assigned the first time it syncs
+	Name     string `json:"name"`         // folder name inside the saves directory
+	SavesDir string `json:"savesDir"`     // relative to the home directory if inside it, absolute otherwise
+	Policy   string `json:"policy"`
+}
+
+// WorldStatus is what the Home screen shows for each synced world
+type WorldStatus struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	SavesDir string     `json:"savesDir"`
+	Policy   string     `json:"policy"`
+	Status   SyncStatus `json:"status"`
+	Pending  bool       `json:"pending"` // has a push waiting in the outbox
+}
+
+func (w WorldConfig) path() string {
+	return filepath.Join(savesPath(w.SavesDir), w.Name)
+}
+
+// the absolute saves folder, see resolvePath for the forms it can be written in
+func savesPath(savesDir string) string {
+	return resolvePath(savesDir)
+}
+
+// identifies the world locally, two worlds can share a name as long as they live in different saves folders.
+// the same folder written two ways is the same world
+func (w WorldConfig) key() string {
+	return filepath.Join(savesPath(w.SavesDir), w.Name)
+}
+
+func (w WorldConfig) canPush() bool {
+	return w.Policy == PolicySync || w.Policy == PolicyPush
+}
+
+func (w WorldConfig) canPull() bool {
+	return w.Policy == PolicySync || w.Policy == PolicyPull
+}
+
+func validPolicy(policy string) bool {
+	switch policy {
+	case PolicySync, PolicyPush, PolicyPull, PolicyPaused:
+		return true
+	}
+	return false
+}
+
+// returns a copy of the synced worlds that is safe to use while the config changes underneath
+func (a *App) worldList() []WorldConfig {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]WorldConfig(nil), a.worlds...)
+}
+
+func (a *App) findWorld(savesDir string, name string) (int, bool) {
+	key := WorldConfig{Name: name, SavesDir: savesDir}.key()
+	for i, w := range a.worlds {
+		if w.key() == key {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// random uuid (version 4) used as a world's id on Drive
+func newWorldID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// NewWorldID is passed to AddWorld and LinkWorld to sync a world as a new one, even though a world with the
+// same name is already on Drive
+const NewWorldID = "new"
+
+// worldNameClashError means a world without an id shares its name with worlds on Drive. a name alone never
+// decides which world on Drive it is, so the user picks one with LinkWorld or syncs it as a new world
+type worldNameClashError struct {
+	world   string
+	matches int
+}
+
+func (e *worldNameClashError) Error() string {
+	if e.matches == 1 {
+		return fmt.Sprintf("a world named %s is already on Drive, choose in MineVCS whether this is that world or a new one", e.world)
+	}
+	return fmt.Sprintf("%d worlds named %s are already on Drive, choose in MineVCS which one this is or sync it as a new world", e.matches, e.world)
+}
+
+// the id a world is added or linked with: one picked from ListCloudWorlds, a fresh one for NewWorldID, or
+// empty to let resolveWorld work it out
+func worldIDFor(id string) (string, error) {
+	if id == NewWorldID {
+		return newWorldID()
+	}
+	return id, nil
+}
+
+// returns the world with its Drive id filled in. a world without one only takes over a world on Drive by
+// name when older versions uploaded it to the root of the Drive, those files are moved into its folder.
+// otherwise it gets a new id, unless worlds with its name are already on Drive and the user has to choose.
+// the id is saved so the name is never used for lookups again
+func (a *App) resolveWorld(w WorldConfig) (WorldConfig, error) {
+	a.idMu.Lock()
+	defer a.idMu.Unlock()
+	a.mu.Lock()
+	if i, ok := a.findWorld(w.SavesDir, w.Name); ok && a.worlds[i].ID != "" {
+		w.ID = a.worlds[i].ID
+	}
+	a.mu.Unlock()
+	if w.ID != "" {
+		return w, nil
+	}
+
+	_, srv, err := drive.InitDrive()
+	if err != nil {
+		return w, err
+	}
+	_, err = drive.FindLegacyFile(srv, w.Name+".zip")
+	if err != nil && !errors.Is(err, drive.ErrNotFound) {
+		return w, err
+	}
+	if errors.Is(err, drive.ErrNotFound) {
+		cloudWorlds, err := drive.ListCloudWorlds(srv)
+		if err != nil {
+			return w, err
+		}
+		matches := 0
+		for _, cw := range cloudWorlds {
+			if cw.Name == w.Name {
+				matches++
+			}
+		}
+		if matches > 0 {
+			return w, &worldNameClashError{world: w.Name, matches: matches}
+		}
+	}
+	id, err := newWorldID()
+	if err != nil {
+		return w, err
+	}
+	if err = a.migrateLegacyWorld(srv, WorldConfig{ID: id, Name: w.Name, SavesDir: w.SavesDir}); err != nil {
+		return w, fmt.Errorf("unable to move %s into the %s folder on Drive: %w", w.Name, drive.AppFolderName, err)
+	}
+	w.ID = id
+	return w, a.saveWorldID(w)
+}
+
+// stores the id resolveWorld or LinkWorld settled on
+func (a *App) saveWorldID(w WorldConfig) error {
+	a.mu.Lock()
+	if i, ok := a.findWorld(w.SavesDir, w.Name); ok {
+		a.worlds[i].ID = w.ID
+	}
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.emitWorlds()
+	return nil
+}
+
+// LinkWorld settles which world on Drive a synced world without an id is, after resolveWorld found others
+// with its name. id is one from ListCloudWorlds, or NewWorldID
+func (a *App) LinkWorld(savesDir string, name string, id string) error {
+	if id == "" {
+		return fmt.Errorf("choose a world on Drive or a new world")
+	}
+	id, err := worldIDFor(id)
+	if err != nil {
+		return err
+	}
+	a.idMu.Lock()
+	defer a.idMu.Unlock()
+	a.mu.Lock()
+	i, ok := a.findWorld(savesDir, name)
+	if !ok {
+		a.mu.Unlock()
+		return fmt.Errorf("world %s is not synced", name)
+	}
+	w := a.worlds[i]
+	a.mu.Unlock()
+	if w.ID != "" {
+		return fmt.Errorf("world %s is already linked to a world on Drive", name)
+	}
+	w.ID = id
+	if err := a.saveWorldID(w); err != nil {
+		return err
+	}
+	a.setWorldStatus(w, "idle", "Linked to Drive")
+	return nil
+}
+
+// moves the files older versions uploaded to the root of the Drive (<name>.zip and its level.dat) into the
+// world's folder. the shared "level.dat" of the single world versions is only taken along with the zip so an
+// unrelated file with that name is left alone
+func (a *App) migrateLegacyWorld(srv *gdrive.Service, w WorldConfig) error {
+	zipFile, err := drive.FindLegacyFile(srv, w.Name+".zip")
+	if errors.Is(err, drive.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.printAndEmit("Moving " + w.Name + " into the " + drive.AppFolderName + " folder on Drive ⏳")
+	levelDat, err := drive.FindLegacyFile(srv, levelDatName(w))
+	if errors.Is(err, drive.ErrNotFound) {
+		// the oldest versions kept a single level.dat for whatever world was pushed last. it only certainly
+		// belongs to this world when this is the only world there. otherwise it's left alone, the next push
+		// uploads a fresh one
+		zips, countErr := drive.CountLegacyZips(srv)
+		if countErr != nil {
+			return countErr
+		}
+		if zips == 1 {
+			levelDat, err = drive.FindLegacyFile(srv, "level.dat")
+		}
+	}
+	if err != nil && !errors.Is(err, drive.ErrNotFound) {
+		return err
+	}
+	if err = drive.AdoptLegacyFile(srv, zipFile, w.ID, w.Name, drive.KindWorld); err != nil {
+		return err
+	}
+	if levelDat != nil {
+		if err = drive.AdoptLegacyFile(srv, levelDat, w.ID, w.Name, drive.KindLevelDat); err != nil {
+			return err
+		}
+	}
+	a.printAndEmit(w.Name + " moved to " + drive.AppFolderName + "/" + w.ID + " ✅")
+	return nil
+}
+
+func (a *App) setWorldStatus(w WorldConfig, state string, message string) {
+	a.mu.Lock()
+	a.worldStatus[w.key()] = SyncStatus{
+		State:   state,
+		Message: message,
+		Time:    time.Now().Format(time.RFC3339),
+	}
+	a.mu.Unlock()
+	a.setSyncStatus(state, w.Name+": "+message)
+	a.emitWorlds()
+}
+
+func (a *App) emitWorlds() {
+	a.emit("worlds", a.ListWorlds())
+}
+
+func (a *App) ListWorlds() []WorldStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	worlds := make([]WorldStatus, 0, len(a.worlds))
+	for _, w := range a.worlds {
+		status, ok := a.worldStatus[w.key()]
+		if !ok {
+			status = SyncStatus{State: "idle"}
+		}
+		worlds = append(worlds, WorldStatus{
+			ID:       w.ID,
+			Name:     w.Name,
+			SavesDir: w.SavesDir,
+			Policy:   w.Policy,
+			Status:   status,
+			Pending:  a.outbox != nil && a.outbox.pending(w),
+		})
+	}
+	return worlds
+}
+
+// AddWorld starts syncing a world. id is the world on Drive it is, from ListCloudWorlds, NewWorldID when the
+// user chose to sync it as a new world, or empty to let resolveWorld work it out on the first sync
+func (a *App) AddWorld(savesDir string, name string, id string) error {
+	if savesDir == "" || name == "" {
+		return fmt.Errorf("saves folder and world name are required")
+	}
+	// the name is a folder inside the saves folder, not a path
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("%q is not a world folder name", name)
+	}
+	id, err := worldIDFor(id)
+	if err != nil {
+		return err
+	}
+	savesDir = normalizePath(savesDir)
+	a.mu.Lock()
+	if _, ok := a.findWorld(savesDir, name); ok {
+		a.mu.Unlock()
+		return nil
+	}
+	a.worlds = append(a.worlds, WorldConfig{ID: id, Name: name, SavesDir: savesDir, Policy: PolicySync})
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.printAndEmit("Now syncing world: " + name + " ✅")
+	a.emitWorlds()
+	a.rewatch()
+	return nil
+}
+
+// stops syncing a world. nothing is deleted locally or on Drive
+func (a *App) RemoveWorld(savesDir string, name string) error {
+	a.mu.Lock()
+	i, ok := a.findWorld(savesDir, name)
+	if !ok {
+		a.mu.Unlock()
+		return fmt.Errorf("world %s is not synced", name)
+	}
+	a.worlds = append(a.worlds[:i], a.worlds[i+1:]...)
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.printAndEmit("Stopped syncing world: " + name)
+	a.emitWorlds()
+	a.rewatch()
+	return nil
+}
+
+func (a *App) SetWorldPolicy(savesDir string, name string, policy string) error {
+	if !validPolicy(policy) {
+		return fmt.Errorf("unknown sync policy %q", policy)
+	}
+	a.mu.Lock()
+	i, ok := a.findWorld(savesDir, name)
+	if !ok {
+		a.mu.Unlock()
+		return fmt.Errorf("world %s is not synced", name)
+	}
+	a.worlds[i].Policy = policy
+	a.mu.Unlock()
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	a.emitWorlds()
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"drive/drive"
+)
+
+var errWatchUnsupported = errors.New("file watching is not supported on this platform")
+
+const (
+	// how often session.lock is checked when there's no native watcher
+	watchPollInterval = 5 * time.Second
+	// even with a watcher every world is rechecked this often in case an event was missed
+	watchSafetyInterval = time.Minute
+	// how often open worlds are checked for a periodic push
+	periodicCheckInterval = 15 * time.Second
+	// how long a push waits for Minecraft to let go of a world it's still closing
+	worldCloseWait = 30 * time.Second
+)
+
+// worldOpenError means a sync was held back because the world is loaded in a running game. replacing its
+// files under the game or zipping half written region files would corrupt it
+type worldOpenError struct {
+	world     string
+	operation string // "push", "pull" or "restore"
+}
+
+func (e *worldOpenError) Error() string {
+	switch e.operation {
+	case "push":
+		return e.world + " is open in Minecraft, it will be pushed once you leave the world"
+	case "restore":
+		return e.world + " is open in Minecraft, leave the world before restoring it"
+	}
+	return e.world + " is open in Minecraft, leave the world before pulling it"
+}
+
+// what the watcher knows about one world
+type worldActivity struct {
+	open      bool      // session.lock is held, the world is loaded in Minecraft
+	lastWrite time.Time // last time anything in the world folder changed
+	lastPush  time.Time // when the world was opened or last pushed while open
+	pushing   bool      // a periodic push is running
+}
+
+// sent back by a periodic push when it's finished
+type periodicResult struct {
+	key     string
+	started time.Time
+	done    bool // false when the world was still being written and should be tried again shortly
+}
+
+func (w WorldConfig) sessionLockPath() string {
+	return filepath.Join(w.path(), "session.lock")
+}
+
+// reports whether the world is currently loaded in Minecraft
+func (w WorldConfig) isOpen() bool {
+	held, err := fileLocked(w.sessionLockPath())
+	return err == nil && held
+}
+
+// waits up to timeout for the game to release the world, returning a *worldOpenError if it's still open.
+// session.lock is locked by the game for as long as the world is loaded and the OS drops the lock when the
+// game exits, so a leftover session.lock file from a crash doesn't count
+func (w WorldConfig) waitUntilClosed(operation string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for w.isOpen() {
+		if time.Now().After(deadline) {
+			return &worldOpenError{world: w.Name, operation: operation}
+		}
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+// the lock a push of the world holds from checking whether it changed until the upload is done, so the watcher
+// pushing a world the moment it's closed and anything else pushing it at the same time can't both pass the hash
+// check and zip and upload the same change twice
+func (a *App) worldPushLock(w WorldConfig) *sync.Mutex {
+	lock, _ := a.pushLocks.LoadOrStore(w.key(), &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// asks the world watcher to start over with the current world list, after worlds were added, removed or
+// replaced by a pull
+func (a *App) rewatch() {
+	select {
+	case a.watchKick <- struct{}{}:
+	default:
+	}
+}
+
+// watches every synced world folder and follows session.lock, so a world is pushed as soon as the player
+// leaves it even if the game stays open. uses the native watcher where there is one and polls otherwise
+func (a *App) runWorldWatcher() {
+	activity := map[string]*worldActivity{}
+	finished := make(chan periodicResult, 16)
+	periodic := time.NewTicker(periodicCheckInterval)
+	defer periodic.Stop()
+	for {
+		worlds := a.worldList()
+		var roots []string
+		for _, w := range worlds {
+			if isDir(w.path()) {
+				roots = append(roots, w.path())
+			}
+			if activity[w.key()] == nil {
+				activity[w.key()] = &worldActivity{open: w.isOpen()}
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		changes := make(chan string, 256)
+		done := make(chan error, 1)
+		go func() {
+			done <- watchDirs(ctx, roots, func(path string) {
+				select {
+				case changes <- path:
+				default: // the periodic recheck catches up with anything dropped here
+				}
+			})
+		}()
+		ticker := time.NewTicker(watchSafetyInterval)
+
+	watching:
+		for {
+			select {
+			case path := <-changes:
+				for _, w := range worlds {
+					if path == "" || path == w.path() || strings.HasPrefix(path, w.path()+string(filepath.Separator)) {
+						activity[w.key()].lastWrite = time.Now()
+						a.checkWorldOpen(w, activity[w.key()])
+					}
+				}
+			case err := <-done:
+				if err != nil && !errors.Is(err, errWatchUnsupported) {
+					println("World watcher stopped, polling instead:", err.Error())
+				}
+				ticker.Reset(watchPollInterval)
+				done = nil
+			case <-ticker.C:
+				for _, w := range worlds {
+					a.checkWorldOpen(w, activity[w.key()])
+				}
+			case <-periodic.C:
+				a.startPeriodicPushes(worlds, activity, finished)
+			case result := <-finished:
+				if state := activity[result.key]; state != nil {
+					state.pushing = false
+					if result.done {
+						state.lastPush = result.started
+					}
+				}
+			case <-a.watchKick:
+				break watching
+			}
+		}
+		cancel()
+		ticker.Stop()
+	}
+}
+
+// compares the world's session.lock with what the watcher last saw and reacts to it being opened or closed
+func (a *App) checkWorldOpen(w WorldConfig, state *worldActivity) {
+	open := w.isOpen()
+	if open == state.open {
+		return
+	}
+	state.open = open
+	if open {
+		state.lastPush = time.Now()
+		a.printAndEmit(w.Name + " opened in Minecraft 🎮")
+		a.setWorldStatus(w, "idle", "Open in Minecraft")
+		return
+	}
+	a.printAndEmit(w.Name + " closed in Minecraft")
+	if !w.canPush() {
+		return
+	}
+	// off the watcher loop like the periodic pushes, so a long upload doesn't hold up the other worlds. the world's
+	// worldPushLock keeps it from overlapping another push of the same world
+	go func() {
+		if authenticated, err := a.CheckIfAuthenticated(); err == nil && authenticated {
+			a.pushIfChanged(w)
+		}
+	}()
+}
+
+// starts a background push for every open world that's due one and whose files have settled since the last
+// autosave
+func (a *App) startPeriodicPushes(worlds []WorldConfig, activity map[string]*worldActivity, finished chan<- periodicResult) {
+	settings := a.transferSettings()
+	if settings.PeriodicPushMinutes == 0 {
+		return
+	}
+	interval := time.Duration(settings.PeriodicPushMinutes) * time.Minute
+	for _, w := range worlds {
+		state := activity[w.key()]
+		if !state.open || state.pushing || !w.canPush() {
+			continue
+		}
+		// lastWrite is only kept up to date by the native watcher, periodicPush checks the files itself
+		if time.Since(state.lastPush) < interval || time.Since(state.lastWrite) < settings.quietPeriod() {
+			continue
+		}
+		state.pushing = true
+		go func(w WorldConfig, since time.Time) {
+			started := time.Now()
+			done := a.periodicPush(w, since, settings)
+			finished <- periodicResult{key: w.key(), started: started, done: done}
+		}(w, state.lastPush)
+	}
+}
+
+// pushes a world while it's open in Minecraft, the one sync allowed to read an open world since it checks
+// the snapshot wasn't written to while it was taken. it runs at low priority: it gives way to any other push, never
+// queues behind the outbox and throws the snapshot away if the game wrote to the world while it was being
+// taken, the push on close covers anything skipped here. returns false if the world should be tried again
+// shortly rather than at the next interval
+func (a *App) periodicPush(w WorldConfig, since time.Time, settings TransferSettings) bool {
+	if authenticated, err := a.CheckIfAuthenticated(); err != nil || !authenticated {
+		return true
+	}
+	w, err := a.resolveWorld(w)
+	if err != nil {
+		println("Periodic push of", w.Name, "skipped:", err.Error())
+		return true
+	}
+	if a.outbox.pending(w) || !drive.Reachable() {
+		return true
+	}
+	latest, err := latestModTime(w.path())
+	if err != nil {
+		println("Periodic push of", w.Name, "skipped:", err.Error())
+		return true
+	}
+	if latest.Before(since) {
+		return true // nothing saved since the last push
+	}
+	if time.Since(latest) < settings.quietPeriod() {
+		return false // still autosaving
+	}
+	// gives way to a push on close or from the bindings, and keeps them waiting until this one is done
+	lock := a.worldPushLock(w)
+	if !lock.TryLock() {
+		return false
+	}
+	defer lock.Unlock()
+	if !a.pushMu.TryLock() {
+		return false
+	}
+	a.pushMu.Unlock()
+
+	started := time.Now()
+	entry, err := a.createSnapshot(w)
+	if err != nil {
+		println("Periodic push of", w.Name, "skipped:", err.Error())
+		return true
+	}
+	defer os.RemoveAll(a.outbox.snapshotDir(entry.ID))
+	if latest, err := latestModTime(w.path()); err != nil || latest.After(started) {
+		println("World", w.Name, "changed while taking a snapshot, trying again shortly")
+		return false
+	}
+	if settings.shouldDefer(a.outbox.snapshotSize(entry), time.Now()) {
+		return true
+	}
+
+	a.printAndEmit("Pushing " + w.Name + " to Drive in the background ⌛️")
+	if err := a.uploadSnapshot(entry); err != nil {
+		a.printAndEmit("Background push of " + w.Name + " failed, it will be pushed when you leave the world: " + err.Error() + " ⚠️")
+		return true
+	}
+	a.printAndEmit("Background push of " + w.Name + " done ✅")
+	a.setWorldStatus(w, "idle", "Open in Minecraft, pushed at "+time.Now().Format("15:04"))
+	return true
+}